@@ -0,0 +1,43 @@
+package mongohelper
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Aggregate wraps the mongo.Database.Collection.Aggregate() method
+// It decodes every resulting document into dest, that must be a pointer to a slice
+//
+// The pipeline parameter must be a slice of stages, like a Pipeline built with PipelineNew(), a mongo.Pipeline
+// or a bson.A. A nil pipeline returns every document in the collection.
+func (l *Link) Aggregate(database, collection string, pipeline interface{}, dest interface{}) error {
+	return l.AggregateCtx(context.Background(), database, collection, pipeline, dest)
+}
+
+// AggregateCtx works like Aggregate, but honors the cancellation and deadline of the given context
+// The configured execution timeout still applies as an upper bound, including the eventual reconnection
+func (l *Link) AggregateCtx(ctx context.Context, database, collection string, pipeline interface{}, dest interface{}) error {
+	if dest == nil {
+		return &OpError{Op: "Aggregate", Database: database, Collection: collection, Kind: ErrNilDestination}
+	}
+
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+
+	op := Operation{Kind: OpAggregate, Database: database, Collection: collection, Pipeline: pipeline, Dest: dest}
+
+	_, err := l.run(ctx, &op, func(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error) {
+		rs, err := client.Database(op.Database).Collection(op.Collection).Aggregate(ctx, op.Pipeline, options.Aggregate())
+
+		if err != nil {
+			return nil, err
+		}
+
+		// All() closes the cursor when it's done
+		return nil, rs.All(ctx, op.Dest)
+	})
+
+	return err
+}
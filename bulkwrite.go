@@ -0,0 +1,250 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WriteModel is one of the writes of a BulkWrite: InsertOneModel, UpdateOneModel, UpdateManyModel, ReplaceOneModel,
+// DeleteOneModel or DeleteManyModel
+type WriteModel interface {
+	// model returns the driver model
+	model() (mongo.WriteModel, error)
+}
+
+// InsertOneModel inserts Document. An ObjectID _id is generated when it has none
+type InsertOneModel struct {
+	Document interface{}
+}
+
+// UpdateOneModel updates the first document matching Filter, or inserts one if Upsert is set
+type UpdateOneModel struct {
+	Filter interface{}
+	Update interface{}
+	Upsert bool
+}
+
+// UpdateManyModel updates every document matching Filter, or inserts one if Upsert is set
+type UpdateManyModel struct {
+	Filter interface{}
+	Update interface{}
+	Upsert bool
+}
+
+// ReplaceOneModel replaces the first document matching Filter, or inserts Replacement if Upsert is set
+type ReplaceOneModel struct {
+	Filter      interface{}
+	Replacement interface{}
+	Upsert      bool
+}
+
+// DeleteOneModel deletes the first document matching Filter
+type DeleteOneModel struct {
+	Filter interface{}
+}
+
+// DeleteManyModel deletes every document matching Filter
+type DeleteManyModel struct {
+	Filter interface{}
+}
+
+func (m InsertOneModel) model() (mongo.WriteModel, error) {
+	return mongo.NewInsertOneModel().SetDocument(m.Document), nil
+}
+
+func (m UpdateOneModel) model() (mongo.WriteModel, error) {
+	if err := ValidateUpdate(m.Update); err != nil {
+		return nil, err
+	}
+
+	return mongo.NewUpdateOneModel().SetFilter(m.Filter).SetUpdate(m.Update).SetUpsert(m.Upsert), nil
+}
+
+func (m UpdateManyModel) model() (mongo.WriteModel, error) {
+	if err := ValidateUpdate(m.Update); err != nil {
+		return nil, err
+	}
+
+	return mongo.NewUpdateManyModel().SetFilter(m.Filter).SetUpdate(m.Update).SetUpsert(m.Upsert), nil
+}
+
+func (m ReplaceOneModel) model() (mongo.WriteModel, error) {
+	return mongo.NewReplaceOneModel().SetFilter(m.Filter).SetReplacement(m.Replacement).SetUpsert(m.Upsert), nil
+}
+
+func (m DeleteOneModel) model() (mongo.WriteModel, error) {
+	return mongo.NewDeleteOneModel().SetFilter(m.Filter), nil
+}
+
+func (m DeleteManyModel) model() (mongo.WriteModel, error) {
+	return mongo.NewDeleteManyModel().SetFilter(m.Filter), nil
+}
+
+// BulkOptions tunes BulkWrite
+type BulkOptions struct {
+	// Ordered stops at the first failed write, in the given order. Otherwise, every write is attempted, in any order
+	Ordered bool
+}
+
+// BulkResult describes the outcome of a BulkWrite, including the writes that succeeded before or besides a failure
+type BulkResult struct {
+	InsertedCount int64
+	MatchedCount  int64
+	ModifiedCount int64
+	DeletedCount  int64
+	UpsertedCount int64
+	// InsertedIDs maps the index of each InsertOneModel to its document _id, of any type
+	InsertedIDs map[int]interface{}
+	// UpsertedIDs maps the index of each upserting model that inserted a document to its _id
+	UpsertedIDs map[int]interface{}
+	// Errors lists the failed writes. With Ordered, there's at most one
+	Errors []BulkItemError
+}
+
+// BulkItemError describes a failed write of a BulkWrite
+type BulkItemError struct {
+	// Index is the position of the model in the given slice
+	Index   int
+	Code    int
+	Message string
+}
+
+// BulkWrite sends every model to the server in as few round trips as possible
+// It returns the result even when some writes fail, along with an *OpError that matches ErrDuplicateKey when any write
+// violated an unique index. Update models are checked by ValidateUpdate() before anything is sent
+func (l *Link) BulkWrite(database, collection string, models []WriteModel, opts BulkOptions) (*BulkResult, error) {
+	return l.BulkWriteCtx(context.Background(), database, collection, models, opts)
+}
+
+// BulkWriteCtx works like BulkWrite, but honors the cancellation and deadline of the given context
+// The configured execution timeout still applies as an upper bound, including the eventual reconnection
+func (l *Link) BulkWriteCtx(ctx context.Context, database, collection string, models []WriteModel, opts BulkOptions) (*BulkResult, error) {
+	// the caller's slice is left untouched, as inserted documents get their _id here
+	models = append([]WriteModel(nil), models...)
+
+	for i, m := range models {
+		var err error
+
+		// _ids are generated here, instead of by the driver, so they can be reported
+		if m == nil {
+			err = mongo.ErrNilDocument
+		} else if ins, ok := m.(InsertOneModel); ok {
+			ins.Document, err = withObjectID(ins.Document)
+
+			models[i] = ins
+		} else {
+			_, err = m.model()
+		}
+
+		if err != nil {
+			kind := errorKind(err)
+
+			if errors.Is(err, ErrInvalidUpdate) {
+				kind = ErrInvalidUpdate
+			}
+
+			return nil, &OpError{Op: "BulkWrite", Database: database, Collection: collection, Kind: kind, Err: fmt.Errorf("model %d: %w", i, err)}
+		}
+	}
+
+	op := Operation{Kind: OpBulkWrite, Database: database, Collection: collection, Models: models, Ordered: opts.Ordered}
+
+	rs, err := l.run(ctx, &op, func(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error) {
+		writes := make([]mongo.WriteModel, len(op.Models))
+
+		for i, m := range op.Models {
+			w, err := m.model()
+
+			if err != nil {
+				return nil, fmt.Errorf("model %d: %w", i, err)
+			}
+
+			writes[i] = w
+		}
+
+		res, err := client.Database(op.Database).Collection(op.Collection).BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(op.Ordered))
+
+		return &OpResult{Bulk: bulkResult(op.Models, op.Ordered, res, err)}, err
+	})
+
+	if rs == nil || rs.Bulk == nil {
+		return nil, err
+	}
+
+	return rs.Bulk, err
+}
+
+// bulkResult converts the driver result, and the write errors of err when it's a mongo.BulkWriteException
+func bulkResult(models []WriteModel, ordered bool, res *mongo.BulkWriteResult, err error) *BulkResult {
+	r := &BulkResult{InsertedIDs: map[int]interface{}{}, UpsertedIDs: map[int]interface{}{}}
+
+	if res != nil {
+		r.InsertedCount = res.InsertedCount
+		r.MatchedCount = res.MatchedCount
+		r.ModifiedCount = res.ModifiedCount
+		r.DeletedCount = res.DeletedCount
+		r.UpsertedCount = res.UpsertedCount
+
+		for i, id := range res.UpsertedIDs {
+			r.UpsertedIDs[int(i)] = id
+		}
+	}
+
+	failed := map[int]bool{}
+
+	// the writes that were attempted: all of them, up to the first failure when ordered
+	attempted := len(models)
+
+	var bwe mongo.BulkWriteException
+
+	switch {
+	case errors.As(err, &bwe):
+		for _, e := range bwe.WriteErrors {
+			r.Errors = append(r.Errors, BulkItemError{Index: e.Index, Code: e.Code, Message: e.Message})
+
+			failed[e.Index] = true
+		}
+
+		if ordered && len(r.Errors) > 0 {
+			attempted = r.Errors[0].Index
+		}
+	case err != nil:
+		// other failures leave the outcome of each write unknown
+		attempted = 0
+	}
+
+	for i := 0; i < attempted; i++ {
+		if ins, ok := models[i].(InsertOneModel); ok && !failed[i] {
+			if doc, ok := ins.Document.(bson.D); ok {
+				r.InsertedIDs[i], _ = docGet(doc, "_id")
+			}
+		}
+	}
+
+	return r
+}
+
+// withObjectID returns document as a bson.D whose first field is _id, generating an ObjectID if it has none
+func withObjectID(document interface{}) (bson.D, error) {
+	if document == nil {
+		return nil, mongo.ErrNilDocument
+	}
+
+	doc, err := toDoc(document)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := docGet(doc, "_id"); ok {
+		return doc, nil
+	}
+
+	return append(bson.D{{Key: "_id", Value: primitive.NewObjectID()}}, doc...), nil
+}
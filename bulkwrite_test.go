@@ -0,0 +1,91 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestLink_BulkWrite(t *testing.T) {
+	var (
+		l    Link
+		seen *Operation
+	)
+
+	l.Use(func(next OpFunc) OpFunc {
+		return func(ctx context.Context, op *Operation) (*OpResult, error) {
+			seen = op
+
+			return next(ctx, op)
+		}
+	})
+
+	models := []WriteModel{
+		InsertOneModel{Document: bson.M{"name": "a"}},
+		InsertOneModel{Document: bson.M{"_id": "custom", "name": "b"}},
+		UpdateManyModel{Filter: bson.M{}, Update: UpdateNew().Inc("n", 1)},
+		DeleteOneModel{Filter: bson.M{"name": "c"}},
+	}
+
+	if _, err := l.BulkWrite(testDB, testCollection, models, BulkOptions{Ordered: true}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("expected the operation to reach the uninitialized link, got %v", err)
+	}
+
+	if seen == nil || seen.Kind != OpBulkWrite || !seen.Ordered || len(seen.Models) != 4 {
+		t.Fatalf("expected the bulk write to go through the interceptors, got %+v", seen)
+	}
+
+	if doc := seen.Models[0].(InsertOneModel).Document.(bson.D); doc[0].Key != "_id" {
+		t.Errorf("expected a generated _id, got %v", doc)
+	}
+
+	if _, ok := models[0].(InsertOneModel).Document.(bson.M); !ok {
+		t.Error("expected the given models to be left untouched")
+	}
+
+	seen = nil
+
+	_, err := l.BulkWrite(testDB, testCollection, []WriteModel{DeleteOneModel{}, UpdateOneModel{Filter: bson.M{}, Update: bson.M{"name": "x"}}}, BulkOptions{})
+
+	if !errors.Is(err, ErrInvalidUpdate) || seen != nil {
+		t.Errorf("expected the invalid update to be rejected before sending, got %v", err)
+	}
+	_, err = l.BulkWrite(testDB, testCollection, []WriteModel{DeleteOneModel{}, nil}, BulkOptions{})
+
+	var oe *OpError
+
+	if !errors.As(err, &oe) || !errors.Is(err, mongo.ErrNilDocument) || !strings.HasPrefix(oe.Err.Error(), "model 1:") || seen != nil {
+		t.Errorf("expected the nil model to be rejected before sending, got %v", err)
+	}
+}
+
+func TestBulkResult(t *testing.T) {
+	models := []WriteModel{
+		InsertOneModel{Document: bson.D{{Key: "_id", Value: 1}}},
+		InsertOneModel{Document: bson.D{{Key: "_id", Value: 2}}},
+		InsertOneModel{Document: bson.D{{Key: "_id", Value: 3}}},
+	}
+
+	res := &mongo.BulkWriteResult{InsertedCount: 1}
+	err := mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{{WriteError: mongo.WriteError{Index: 1, Code: 11000, Message: "duplicate"}}}}
+
+	ordered := bulkResult(models, true, res, err)
+
+	if len(ordered.InsertedIDs) != 1 || ordered.InsertedIDs[0] != 1 || len(ordered.Errors) != 1 || ordered.Errors[0].Index != 1 {
+		t.Errorf("expected only the first insertion to succeed, got %+v", ordered)
+	}
+
+	unordered := bulkResult(models, false, &mongo.BulkWriteResult{InsertedCount: 2}, err)
+
+	if len(unordered.InsertedIDs) != 2 || unordered.InsertedIDs[2] != 3 {
+		t.Errorf("expected every insertion but the failed one to succeed, got %+v", unordered)
+	}
+
+	if errorKind(err) != ErrDuplicateKey {
+		t.Errorf("expected the bulk write error to be classified as duplicate key")
+	}
+}
@@ -3,10 +3,10 @@ package mongohelper
 import "go.mongodb.org/mongo-driver/mongo"
 
 // Collection returns a collection from the target database
-func (l Link) Collection(database, collection string) (*mongo.Collection, error) {
-	if err := l.linkCheck("link.Collection"); err != nil {
+func (l *Link) Collection(database, collection string) (*mongo.Collection, error) {
+	if err := l.linkCheck("link.Collection", database, collection); err != nil {
 		return nil, err
 	}
 
-	return l.client.Database(database).Collection(collection), nil
+	return l.currentClient().Database(database).Collection(collection), nil
 }
@@ -2,39 +2,77 @@ package mongohelper
 
 import (
 	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"time"
 )
 
-func (l *Link) defineLink(opts *options.ClientOptions) error {
-	ctx, cancel := context.WithTimeout(context.Background(), l.connTimeout())
+// defineLink creates a new client, replacing and closing the current one, if any
+func (l *Link) defineLink(ctx context.Context, opts *options.ClientOptions) error {
+	ctx, cancel := context.WithTimeout(ctx, l.connTimeout())
 
 	defer cancel()
 
 	c, err := mongo.Connect(ctx, opts)
 
 	if err != nil {
-		l.log("link.defineLink.mongo.Connect", err.Error())
+		l.logger().Error("mongo.Connect failed", Field{FieldRoutine, "link.defineLink"}, Field{FieldError, err})
 
 		return err
 	}
 
-	l.client = c
+	l.mu.Lock()
+
+	old := l.client
+
+	// Disconnect() may have been called while connecting
+	closed := l.State() == StateClosed
+
+	if closed {
+		old = c
+	} else {
+		l.client = c
+	}
+
+	l.mu.Unlock()
+
+	if old != nil {
+		go l.closeClient(old)
+	}
+
+	if closed {
+		return errLinkClosed
+	}
 
 	return nil
 }
 
-func (l *Link) ping() error {
-	ctx, cancel := context.WithTimeout(context.Background(), l.connTimeout())
+// closeClient disconnects a client that is not used anymore. Operations still using it will fail with
+// mongo.ErrClientDisconnected and repeat themselves with the current client
+func (l *Link) closeClient(c *mongo.Client) {
+	if err := l.release(c.Disconnect); err != nil && !errors.Is(err, mongo.ErrClientDisconnected) {
+		l.logger().Warn("closing replaced client failed", Field{FieldRoutine, "link.closeClient"}, Field{FieldError, err})
+	}
+}
+
+func (l *Link) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, l.connTimeout())
 
 	defer cancel()
 
-	err := l.client.Ping(ctx, readpref.Primary())
+	start := time.Now()
+
+	err := l.currentClient().Ping(ctx, readpref.Primary())
+
+	l.metrics().ObservePing(time.Since(start), err)
 
 	if err != nil {
-		l.log("link.ping", err.Error())
+		l.logger().Warn("ping failed", Field{FieldRoutine, "link.ping"}, Field{FieldDuration, time.Since(start)}, Field{FieldError, err})
+	} else {
+		l.logger().Debug("ping", Field{FieldRoutine, "link.ping"}, Field{FieldDuration, time.Since(start)})
 	}
 
 	return err
@@ -42,29 +80,115 @@ func (l *Link) ping() error {
 
 // connect tries to conect database using the given options
 func (l *Link) connect() error {
+	return l.connectCtx(context.Background(), l.insistOnFail())
+}
+
+// reconnectFlight is a reconnection in progress, shared by every routine that needs it
+type reconnectFlight struct {
+	done chan struct{}
+	err  error
+}
+
+// reconnect replaces the client that failed, unless another routine already did it
+// Only one reconnection runs at a time: concurrent callers wait for it, or give up when their ctx is done
+// If failed is nil, the current client is replaced anyway
+func (l *Link) reconnect(ctx context.Context, insist bool, failed *mongo.Client) error {
+	l.flightMu.Lock()
+
+	if failed != nil && failed != l.currentClient() {
+		l.flightMu.Unlock()
+
+		return nil
+	}
+
+	if f := l.flight; f != nil {
+		l.flightMu.Unlock()
+
+		select {
+		case <-f.done:
+			return f.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	f := &reconnectFlight{done: make(chan struct{})}
+	l.flight = f
+
+	l.flightMu.Unlock()
+
+	f.err = l.connectCtx(ctx, insist)
+
+	l.flightMu.Lock()
+	l.flight = nil
+	l.flightMu.Unlock()
+
+	close(f.done)
+
+	return f.err
+}
+
+// connectCtx tries to conect database using the given options, giving up when ctx is done
+// If insist is true, failed attempts are repeated while the options limits allow
+func (l *Link) connectCtx(ctx context.Context, insist bool) error {
+	l.setState(StateConnecting)
+
 	opts := options.Client().ApplyURI(l.connectionString())
 	opts.SetConnectTimeout(l.connTimeout())
 	opts.SetMaxConnIdleTime(8 * time.Hour)
 	opts.SetSocketTimeout(l.execTimeout())
 	opts.SetMinPoolSize(10)
 	opts.SetAppName(l.appName())
+	opts.SetMonitor(l.commandMonitor())
+	opts.SetPoolMonitor(&event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			l.metrics().ObservePoolEvent(e.Type, e.Address)
+		},
+	})
+
+	// the first connection, with New(), isn't a reconnection for the metrics
+	reconnecting := l.currentClient() != nil
 
 	// It's not possible to restore from errors in options validation
-	if err := l.defineLink(opts); err != nil {
+	if err := l.defineLink(ctx, opts); err != nil {
+		l.setState(StateDegraded)
+
+		l.emit(EventReconnectGaveUp, "link.defineLink", err)
+
 		return err
 	}
 
 	for {
-		err := l.ping()
+		err := l.ping(ctx)
+
+		if reconnecting {
+			l.metrics().ObserveReconnect(l.tracker.status().attempts+1, err)
+		}
 
 		if err != nil {
-			l.log("link.mongo.Ping", err.Error())
+			l.tracker.failure()
+
+			l.logger().Warn("connection attempt failed", Field{FieldRoutine, "link.connect"}, Field{FieldAttempt, l.tracker.status().attempts}, Field{FieldError, err})
+
+			l.emit(EventPingFailed, "link.connect", err)
+
+			if insist && l.canInsist() {
+				if err := l.wait(ctx, l.tracker.status().attempts); err != nil {
+					l.setState(StateDegraded)
+
+					l.emit(EventReconnectGaveUp, "link.connect", err)
+
+					return err
+				}
+
+				l.emit(EventReconnectAttempt, "link.connect", err)
 
-			if l.insistOnFail() && l.canInsist() {
-				l.wait()
-				l.increment()
 				continue
 			} else {
+				l.setState(StateDegraded)
+
+				l.emit(EventReconnectGaveUp, "link.connect", err)
+
 				return err
 			}
 		}
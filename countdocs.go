@@ -2,9 +2,7 @@ package mongohelper
 
 import (
 	"context"
-	"errors"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // CountDocs wraps the mongo.Database.Collection.CountDocuments() method
@@ -13,35 +11,26 @@ import (
 // The filter parameter must be a document and can be used to select which documents contribute to the count. It
 // cannot be nil. An empty document (e.g. bson.D{}) should be used to count all documents in the collection. This will
 // result in a full collection scan.
-func (l *Link) CountDocs(database, collection string, filter interface{}) (int64, error) {
-	if err := l.linkCheck("link.CountDocs"); err != nil {
-		return 0, err
-	}
+//
+// The opts parameter limits, skips and hints the count, like Limit(1000) to stop counting early
+func (l *Link) CountDocs(database, collection string, filter interface{}, opts ...FindOption) (int64, error) {
+	return l.CountDocsCtx(context.Background(), database, collection, filter, opts...)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), l.execTimeout())
+// CountDocsCtx works like CountDocs, but honors the cancellation and deadline of the given context
+// The configured execution timeout still applies as an upper bound, including the eventual reconnection
+func (l *Link) CountDocsCtx(ctx context.Context, database, collection string, filter interface{}, opts ...FindOption) (int64, error) {
+	op := Operation{Kind: OpCountDocs, Database: database, Collection: collection, Filter: filter, Options: findOptions(opts)}
 
-	defer cancel()
+	rs, err := l.run(ctx, &op, func(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error) {
+		n, err := client.Database(op.Database).Collection(op.Collection).CountDocuments(ctx, op.Filter, op.Options.count())
 
-	n, err := l.client.Database(database).Collection(collection).CountDocuments(ctx, filter, options.Count())
+		return &OpResult{Count: n}, err
+	})
 
 	if err != nil {
-		// If not connected, try once again
-		if errors.Is(err, mongo.ErrClientDisconnected) {
-			if err = l.connect(); err != nil {
-				return 0, err
-			}
-
-			ctx2, cancel2 := context.WithTimeout(context.Background(), l.execTimeout())
-
-			defer cancel2()
-
-			if n, err = l.client.Database(database).Collection(collection).CountDocuments(ctx2, filter, options.Count()); err != nil {
-				return 0, err
-			}
-		} else {
-			return 0, err
-		}
+		return 0, err
 	}
 
-	return n, nil
+	return rs.Count, nil
 }
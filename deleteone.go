@@ -2,7 +2,6 @@ package mongohelper
 
 import (
 	"context"
-	"errors"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -10,33 +9,26 @@ import (
 // DeleteOne wraps the mongo.Database.Collection.DeleteOne() method
 // It returns the number of affected records and an error
 func (l *Link) DeleteOne(database, collection string, filter interface{}) (int64, error) {
-	if err := l.linkCheck("link.DeleteOne"); err != nil {
-		return 0, err
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), l.execTimeout())
-
-	defer cancel()
+	return l.DeleteOneCtx(context.Background(), database, collection, filter)
+}
 
-	rs, err := l.client.Database(database).Collection(collection).DeleteOne(ctx, filter, options.Delete())
+// DeleteOneCtx works like DeleteOne, but honors the cancellation and deadline of the given context
+// The configured execution timeout still applies as an upper bound, including the eventual reconnection
+func (l *Link) DeleteOneCtx(ctx context.Context, database, collection string, filter interface{}) (int64, error) {
+	op := Operation{Kind: OpDeleteOne, Database: database, Collection: collection, Filter: filter}
 
-	if err != nil {
-		// If not connected, try once again
-		if errors.Is(err, mongo.ErrClientDisconnected) {
-			if err = l.connect(); err != nil {
-				return 0, err
-			}
+	rs, err := l.run(ctx, &op, func(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error) {
+		rs, err := client.Database(op.Database).Collection(op.Collection).DeleteOne(ctx, op.Filter, options.Delete())
 
-			ctx2, cancel2 := context.WithTimeout(context.Background(), l.execTimeout())
+		if err != nil {
+			return nil, err
+		}
 
-			defer cancel2()
+		return &OpResult{DeletedCount: rs.DeletedCount}, nil
+	})
 
-			if rs, err = l.client.Database(database).Collection(collection).DeleteOne(ctx2, filter, options.Delete()); err != nil {
-				return 0, err
-			}
-		} else {
-			return 0, err
-		}
+	if err != nil {
+		return 0, err
 	}
 
 	return rs.DeletedCount, nil
@@ -1,16 +1,40 @@
 package mongohelper
 
-import "context"
-
-// Disconnect closes the client connection with database
+// Disconnect stops the supervisor, if any, and closes the client connection with database
 func (l *Link) Disconnect() {
-	if l.client != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), l.connTimeout())
+	if l.supervisor != nil {
+		l.supervisor.stop()
+	}
+
+	l.watchersMu.Lock()
+
+	watchers := make([]*Watcher, 0, len(l.watchers))
+
+	for w := range l.watchers {
+		watchers = append(watchers, w)
+	}
+
+	l.watchersMu.Unlock()
 
-		defer cancel()
+	for _, w := range watchers {
+		_ = w.Stop()
+	}
+
+	l.mu.Lock()
+
+	l.setState(StateClosed)
+
+	client := l.client
 
-		if err := l.client.Disconnect(ctx); err != nil {
-			l.log("Disconnect", err.Error())
+	l.mu.Unlock()
+
+	var err error
+
+	if client != nil {
+		if err = l.release(client.Disconnect); err != nil {
+			l.logger().Error("disconnection failed", Field{FieldRoutine, "Disconnect"}, Field{FieldError, err})
 		}
 	}
+
+	l.emit(EventDisconnected, "Disconnect", err)
 }
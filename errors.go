@@ -0,0 +1,239 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrInvalidOption is matched, through errors.Is(), by every error returned from Options.Validate()
+var ErrInvalidOption = errors.New("mongohelper: invalid option")
+
+// OptionError describes an option rejected by Options.Validate()
+type OptionError struct {
+	// Option is the name of the rejected parameter
+	Option string
+	// Value is the rejected value
+	Value interface{}
+	// Reason explains why the value was rejected
+	Reason string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("mongohelper: invalid %s %v: %s", e.Option, e.Value, e.Reason)
+}
+
+// Unwrap allows errors.Is(err, ErrInvalidOption)
+func (e *OptionError) Unwrap() error {
+	return ErrInvalidOption
+}
+
+// ErrInvalidUpdate is matched, through errors.Is(), by the errors of UpdateOne and UpdateMany calls whose update
+// document was rejected before reaching the server
+var ErrInvalidUpdate = errors.New("mongohelper: invalid update")
+
+// UpdateError describes an update document rejected by ValidateUpdate()
+type UpdateError struct {
+	// Field is the offending top level key, if any
+	Field string
+	// Reason explains why the update was rejected
+	Reason string
+}
+
+func (e *UpdateError) Error() string {
+	if e.Field == "" {
+		return "mongohelper: invalid update: " + e.Reason
+	}
+
+	return fmt.Sprintf("mongohelper: invalid update at %s: %s", e.Field, e.Reason)
+}
+
+// Unwrap allows errors.Is(err, ErrInvalidUpdate)
+func (e *UpdateError) Unwrap() error {
+	return ErrInvalidUpdate
+}
+
+// MigrationError is returned when a migration fails to be applied, reverted or recorded
+type MigrationError struct {
+	Version     uint64
+	Description string
+	Err         error
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("mongohelper: migration %d (%s): %v", e.Version, e.Description, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// ErrNotConnected is returned when the link was not initialized, or was closed with Disconnect(), and when no
+	// server is available
+	ErrNotConnected = errors.New("mongohelper: not connected")
+	// ErrNilDestination is returned when a nil destination is given to receive the results
+	ErrNilDestination = errors.New(`mongohelper: given "dest" is null`)
+	// ErrNotFound is returned when no document matches the filter. It also matches mongo.ErrNoDocuments
+	ErrNotFound = fmt.Errorf("mongohelper: document not found: %w", mongo.ErrNoDocuments)
+	// ErrDuplicateKey is returned when a write violates an unique index
+	ErrDuplicateKey = errors.New("mongohelper: duplicate key")
+	// ErrTimeout is returned when an operation exceeds its deadline, on client or server side
+	ErrTimeout = errors.New("mongohelper: timeout")
+	// ErrInvalidPageRequest is returned by Paginate for malformed tokens, or tokens issued for another sort order
+	ErrInvalidPageRequest = errors.New("mongohelper: invalid page request")
+	// ErrStopIteration may be returned by Iterate and Watch callbacks to stop early, with no error
+	ErrStopIteration = errors.New("mongohelper: stop iteration")
+	// ErrInvalidIndexSpec is returned by EnsureIndexes for specs the server would reject
+	ErrInvalidIndexSpec = errors.New("mongohelper: invalid index spec")
+	// ErrInvalidMigration is returned by Migrator.Register for migrations that can't be registered
+	ErrInvalidMigration = errors.New("mongohelper: invalid migration")
+	// ErrIrreversibleMigration is returned by Migrator.MigrateDown for migrations without Down, or not registered
+	ErrIrreversibleMigration = errors.New("mongohelper: irreversible migration")
+	// ErrMigrationLocked is returned when another instance holds the migrations lock for too long, or took it over
+	ErrMigrationLocked = errors.New("mongohelper: migrations locked by another instance")
+	// ErrReconnectFailed is returned when an operation found the client disconnected and couldn't reconnect
+	ErrReconnectFailed = errors.New("mongohelper: reconnection failed")
+)
+
+var (
+	errUninitialized = errors.New("use of uninitialized connection")
+	errLinkClosed    = errors.New("use of closed connection")
+)
+
+// OpError describes a failed operation
+// Both the classification sentinel (ErrNotFound, ErrDuplicateKey, ...) and the underlying driver error
+// can be matched with errors.Is() and errors.As()
+type OpError struct {
+	// Op is the operation name, like "Find" or "UpdateOne"
+	Op         string
+	Database   string
+	Collection string
+	// Attempts is how many times the operation was executed
+	Attempts uint
+	// Kind is the classification sentinel, or nil if the error doesn't fit any of them
+	Kind error
+	// Err is the underlying error
+	Err error
+}
+
+func (e *OpError) Error() string {
+	s := "mongohelper: " + e.Op
+
+	if e.Database != "" {
+		s += " " + e.Database
+
+		if e.Collection != "" {
+			s += "." + e.Collection
+		}
+	}
+
+	if e.Attempts > 1 {
+		s += fmt.Sprintf(" after %d attempts", e.Attempts)
+	}
+
+	switch {
+	case e.Err != nil:
+		return s + ": " + e.Err.Error()
+	case e.Kind != nil:
+		return s + ": " + e.Kind.Error()
+	}
+
+	return s
+}
+
+// Unwrap exposes both Kind and Err to errors.Is() and errors.As()
+func (e *OpError) Unwrap() []error {
+	var a []error
+
+	if e.Kind != nil {
+		a = append(a, e.Kind)
+	}
+
+	if e.Err != nil {
+		a = append(a, e.Err)
+	}
+
+	return a
+}
+
+// opError returns an *OpError for the given routine, classifying err, or nil if err is nil
+func opError(routine, database, collection string, attempts uint, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &OpError{
+		Op:         opName(routine),
+		Database:   database,
+		Collection: collection,
+		Attempts:   attempts,
+		Kind:       errorKind(err),
+		Err:        err,
+	}
+}
+
+// opName returns the operation name of the given routine, like "Find" for "link.Find"
+func opName(routine string) string {
+	return strings.TrimPrefix(routine, "link.")
+}
+
+// duplicateKeyCodes are the server error codes for unique index violations
+var duplicateKeyCodes = map[int]bool{11000: true, 11001: true, 12582: true}
+
+// errorKind returns the sentinel that classifies err, or nil
+func errorKind(err error) error {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return ErrNotFound
+	case errors.Is(err, mongo.ErrClientDisconnected), isServerSelectionError(err):
+		return ErrNotConnected
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTimeout
+	}
+
+	var we mongo.WriteException
+
+	if errors.As(err, &we) {
+		for _, e := range we.WriteErrors {
+			if duplicateKeyCodes[e.Code] {
+				return ErrDuplicateKey
+			}
+		}
+	}
+
+	var bwe mongo.BulkWriteException
+
+	if errors.As(err, &bwe) {
+		for _, e := range bwe.WriteErrors {
+			if duplicateKeyCodes[e.Code] {
+				return ErrDuplicateKey
+			}
+		}
+	}
+
+	var ce mongo.CommandError
+
+	if errors.As(err, &ce) {
+		if duplicateKeyCodes[int(ce.Code)] {
+			return ErrDuplicateKey
+		}
+
+		if ce.IsMaxTimeMSExpiredError() {
+			return ErrTimeout
+		}
+	}
+
+	var ne net.Error
+
+	if errors.As(err, &ne) && ne.Timeout() {
+		return ErrTimeout
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestOpError(t *testing.T) {
+	dup := mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: "E11000 duplicate key error"}}}
+
+	// the error of a real operation on a server that's down
+	_, unavailable := unreachableLink(t).currentClient().Database(testDB).Collection(testCollection).CountDocuments(context.Background(), bson.M{})
+
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{mongo.ErrNoDocuments, ErrNotFound},
+		{mongo.ErrClientDisconnected, ErrNotConnected},
+		{unavailable, ErrNotConnected},
+		{context.DeadlineExceeded, ErrTimeout},
+		{mongo.CommandError{Code: 50, Name: "MaxTimeMSExpired"}, ErrTimeout},
+		{dup, ErrDuplicateKey},
+		{mongo.CommandError{Code: 11000}, ErrDuplicateKey},
+		{errors.New("anything"), nil},
+	}
+
+	for _, c := range cases {
+		err := opError("link.InsertOne", testDB, testCollection, 2, c.err)
+
+		var oe *OpError
+
+		if !errors.As(err, &oe) || oe.Op != "InsertOne" || oe.Attempts != 2 || oe.Kind != c.kind {
+			t.Errorf("%v: unexpected %#v", c.err, oe)
+		}
+
+		if c.kind != nil && !errors.Is(err, c.kind) {
+			t.Errorf("%v: expected errors.Is(err, %v)", c.err, c.kind)
+		}
+
+		if !reflect.DeepEqual(oe.Err, c.err) {
+			t.Errorf("%v: expected the driver error to be kept", c.err)
+		}
+	}
+
+	if o := outcome(opError("link.CountDocs", testDB, testCollection, 1, unavailable)); o != OutcomeNotConnected {
+		t.Errorf("expected outcome %s for an unavailable server, got %s", OutcomeNotConnected, o)
+	}
+
+	if err := opError("link.FindOne", testDB, testCollection, 1, mongo.ErrNoDocuments); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Error("expected ErrNotFound to keep matching mongo.ErrNoDocuments")
+	}
+
+	if err := opError("link.InsertOne", testDB, testCollection, 1, dup); !errors.As(err, new(mongo.WriteException)) {
+		t.Error("expected the driver error to be reachable with errors.As")
+	}
+
+	if err := opError("link.Watch", testDB, testCollection, 1, nil); err != nil {
+		t.Errorf("expected no error from a nil error, got %v", err)
+	}
+
+	if s := (&OpError{Op: "Watch", Database: testDB, Collection: testCollection}).Error(); s != "mongohelper: Watch "+testDB+"."+testCollection {
+		t.Errorf("unexpected message for an OpError with neither Kind nor Err: %q", s)
+	}
+}
+
+func TestLink_errors(t *testing.T) {
+	var l Link
+
+	if err := l.Find(testDB, testCollection, nil, &[]testDocStruct{}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("expected ErrNotConnected, got %v", err)
+	}
+
+	if err := l.FindOne(testDB, testCollection, nil, nil); !errors.Is(err, ErrNilDestination) {
+		t.Errorf("expected ErrNilDestination, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package mongohelper
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType identifies what happened to the link with database
+type EventType int
+
+const (
+	// EventConnected is emitted when a (re)connection succeeds
+	EventConnected EventType = iota
+	// EventPingFailed is emitted when database doesn't answer a ping
+	EventPingFailed
+	// EventReconnectAttempt is emitted before each new (re)connection attempt
+	EventReconnectAttempt
+	// EventReconnectGaveUp is emitted when the engine stops trying to (re)connect
+	EventReconnectGaveUp
+	// EventDisconnected is emitted when Disconnect() is called
+	EventDisconnected
+	// EventOperationRetried is emitted before each retry of a failed operation, transaction or change stream, whether
+	// or not the link was reconnected
+	EventOperationRetried
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventConnected:
+		return "connected"
+	case EventPingFailed:
+		return "ping failed"
+	case EventReconnectAttempt:
+		return "reconnect attempt"
+	case EventReconnectGaveUp:
+		return "reconnect gave up"
+	case EventDisconnected:
+		return "disconnected"
+	case EventOperationRetried:
+		return "operation retried"
+	}
+
+	return "unknown"
+}
+
+// Event describes a change in the link with database
+type Event struct {
+	Type EventType
+	// Time is when the event happened
+	Time time.Time
+	// Routine is the engine routine that emitted the event, like "link.Find"
+	Routine string
+	// Attempt is the (re)connection attempts counter when the event happened
+	Attempt uint
+	// AttemptsLimit is the configured maximum number of (re)connection attempts, or 0 for infinite
+	AttemptsLimit uint
+	// TimeLimit is the configured maximum time trying to (re)connect, or 0 for infinite
+	TimeLimit time.Duration
+	// OutageStart is when the current outage began, or zero if there's none
+	OutageStart time.Time
+	// LastConnection is when the last connection succeeded
+	LastConnection time.Time
+	// Err is the underlying error, if any
+	Err error
+}
+
+// events keeps the subscribed event handlers
+type events struct {
+	mu       sync.Mutex
+	handlers map[int]func(Event)
+	next     int
+}
+
+// Subscribe registers handler to be called on every Event, until the returned function is called
+// Handlers are called synchronously, from the routine that emitted the event, so they shouldn't block
+func (l *Link) Subscribe(handler func(Event)) (unsubscribe func()) {
+	l.events.mu.Lock()
+
+	defer l.events.mu.Unlock()
+
+	if l.events.handlers == nil {
+		l.events.handlers = make(map[int]func(Event))
+	}
+
+	id := l.events.next
+	l.events.next++
+	l.events.handlers[id] = handler
+
+	return func() {
+		l.events.mu.Lock()
+
+		defer l.events.mu.Unlock()
+
+		delete(l.events.handlers, id)
+	}
+}
+
+// emit delivers an Event of the given type to every subscribed handler
+func (l *Link) emit(t EventType, routine string, err error) {
+	l.events.mu.Lock()
+
+	if len(l.events.handlers) == 0 {
+		l.events.mu.Unlock()
+
+		return
+	}
+
+	handlers := make([]func(Event), 0, len(l.events.handlers))
+
+	for _, h := range l.events.handlers {
+		handlers = append(handlers, h)
+	}
+
+	l.events.mu.Unlock()
+
+	status := l.tracker.status()
+
+	e := Event{
+		Type:           t,
+		Time:           time.Now(),
+		Routine:        routine,
+		Attempt:        status.attempts,
+		AttemptsLimit:  l.options.reconnectionAttemptsLimit,
+		TimeLimit:      l.options.reconnectionTimeLimit,
+		OutageStart:    status.outageStart,
+		LastConnection: status.lastConnection,
+		Err:            err,
+	}
+
+	for _, h := range handlers {
+		h(e)
+	}
+}
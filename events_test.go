@@ -0,0 +1,38 @@
+package mongohelper
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLink_Subscribe(t *testing.T) {
+	l := linkNew(*NewOptions("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=50", WithMaxAttempts(2)))
+	l.options.connTimeout = 50 * time.Millisecond
+
+	var got []Event
+
+	unsubscribe := l.Subscribe(func(e Event) {
+		got = append(got, e)
+	})
+
+	if err := l.connectCtx(context.Background(), false); err == nil {
+		t.Fatal("expected connection to fail")
+	}
+
+	if len(got) != 2 || got[0].Type != EventPingFailed || got[1].Type != EventReconnectGaveUp {
+		t.Fatalf("expected ping failed and gave up events, got %v", got)
+	}
+
+	if got[1].Err == nil || got[1].AttemptsLimit != 2 || got[1].Time.IsZero() {
+		t.Errorf("incomplete event: %+v", got[1])
+	}
+
+	unsubscribe()
+
+	l.Disconnect()
+
+	if len(got) != 2 {
+		t.Errorf("expected no events after unsubscribe, got %v", got[2:])
+	}
+}
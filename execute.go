@@ -0,0 +1,89 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// execute runs fn within ctx, bounded by the configured execution timeout
+// When fn fails with an error the retry policy classifies as retryable, it's repeated up to the configured number of
+// operation retries, inside the same context budget. A disconnected client is reconnected before the next attempt
+// Unless idempotent is set, only failures that prove fn didn't reach the server are retried, as the server may have
+// applied a write that failed afterwards
+// Inside a transaction nothing is retried, since WithTransaction retries the whole transaction instead
+// Every error returned is an *OpError
+func (l *Link) execute(ctx context.Context, routine, database, collection string, idempotent bool, fn func(ctx context.Context, client *mongo.Client) error) error {
+	if err := l.linkCheck(routine, database, collection); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, l.execTimeout())
+
+	defer cancel()
+
+	policy := l.retryPolicy()
+
+	retries := l.options.operationRetries
+
+	if inTransaction(ctx) {
+		retries = 0
+	}
+
+	for retry := uint(1); ; retry++ {
+		start := time.Now()
+
+		client := l.currentClient()
+
+		// a transaction keeps the client its session was started on
+		if c := txClient(ctx); c != nil {
+			client = c
+		}
+
+		err := fn(ctx, client)
+
+		fields := []Field{{FieldRoutine, routine}, {FieldDatabase, database}, {FieldCollection, collection}, {FieldDuration, time.Since(start)}, {FieldAttempt, retry}}
+
+		if err == nil {
+			l.logger().Debug("operation succeeded", fields...)
+
+			return nil
+		}
+
+		// Not finding a document is an expected outcome, not a failure
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			l.logger().Debug("no documents found", fields...)
+
+			return opError(routine, database, collection, retry, err)
+		}
+
+		if retry > retries || !policy.Retryable(err) || !idempotent && !notSent(err) {
+			l.logger().Error("operation failed", append(fields, Field{FieldError, err})...)
+
+			return opError(routine, database, collection, retry, err)
+		}
+
+		l.logger().Warn("operation failed, retrying", append(fields, Field{FieldError, err})...)
+
+		// A disconnected client must be replaced. Other failures may be solved by the driver itself, after a while
+		if errors.Is(err, mongo.ErrClientDisconnected) {
+			rctx, span := l.startSpan(ctx, "reconnect", Field{AttrDBSystem, "mongodb"}, Field{FieldAttempt, retry})
+
+			err := l.reconnect(rctx, l.insistOnFail(), client)
+
+			endSpan(span, err)
+
+			if err != nil {
+				return &OpError{Op: opName(routine), Database: database, Collection: collection, Attempts: retry, Kind: ErrReconnectFailed, Err: err}
+			}
+		} else if sleep(ctx, policy.Backoff(retry)) != nil {
+			return opError(routine, database, collection, retry, err)
+		}
+
+		spanFromContext(ctx).AddEvent("retry", Field{FieldAttempt, retry + 1}, Field{FieldError, err})
+
+		l.emit(EventOperationRetried, routine, err)
+	}
+}
@@ -0,0 +1,262 @@
+package mongohelper
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Filter is a query filter built fluently, like Where("age").Gt(18).And(Where("status").In("a", "b"))
+// It marshals as the bson.D it represents, so it's accepted anywhere a filter is. The zero Filter matches everything
+// Filters are immutable: every method returns a new one
+type Filter struct {
+	d bson.D
+}
+
+// Condition is a field waiting for its operator, as returned by Where
+type Condition struct {
+	field string
+	not   bool
+}
+
+// Where starts a condition on the given field. Dotted paths reach embedded documents and arrays
+func Where(field string) Condition {
+	return Condition{field: field}
+}
+
+// And returns a filter matching documents that satisfy every given filter
+func And(filters ...Filter) Filter {
+	return Filter{}.combine("$and", filters)
+}
+
+// Or returns a filter matching documents that satisfy at least one of the given filters
+func Or(filters ...Filter) Filter {
+	return Filter{}.combine("$or", filters)
+}
+
+// Nor returns a filter matching documents that satisfy none of the given filters
+func Nor(filters ...Filter) Filter {
+	return Filter{}.combine("$nor", filters)
+}
+
+// Expr returns a filter that evaluates an aggregation expression, like bson.M{"$gt": bson.A{"$spent", "$budget"}}
+func Expr(expression interface{}) Filter {
+	return Filter{bson.D{{Key: "$expr", Value: expression}}}
+}
+
+// Text returns a filter that performs a text search on the collection text index
+func Text(search string) Filter {
+	return Filter{bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: search}}}}}
+}
+
+// And returns a filter matching documents that satisfy f and every given filter
+func (f Filter) And(filters ...Filter) Filter {
+	return f.combine("$and", filters)
+}
+
+// Or returns a filter matching documents that satisfy f or any of the given filters
+func (f Filter) Or(filters ...Filter) Filter {
+	return f.combine("$or", filters)
+}
+
+// Nor returns a filter matching documents that satisfy neither f nor any of the given filters
+func (f Filter) Nor(filters ...Filter) Filter {
+	return f.combine("$nor", filters)
+}
+
+// D returns the filter document
+func (f Filter) D() bson.D {
+	if f.d == nil {
+		return bson.D{}
+	}
+
+	return f.d
+}
+
+// MarshalBSON encodes the filter document, so Filter can be given to the driver as is
+func (f Filter) MarshalBSON() ([]byte, error) {
+	return bson.Marshal(f.D())
+}
+
+// combine joins f and others with a logical operator, extending f when it's already made of the same operator
+func (f Filter) combine(op string, others []Filter) Filter {
+	clauses := bson.A{}
+
+	if len(f.d) == 1 && f.d[0].Key == op {
+		clauses = append(clauses, f.d[0].Value.(bson.A)...)
+	} else if len(f.d) > 0 {
+		clauses = append(clauses, f.d)
+	}
+
+	for _, o := range others {
+		if len(o.d) > 0 {
+			clauses = append(clauses, o.d)
+		}
+	}
+
+	if len(clauses) == 0 {
+		return Filter{}
+	}
+
+	if len(clauses) == 1 && op != "$nor" {
+		return Filter{clauses[0].(bson.D)}
+	}
+
+	return Filter{bson.D{{Key: op, Value: clauses}}}
+}
+
+// Not negates the operator that follows, like Where("age").Not().Gt(18)
+func (c Condition) Not() Condition {
+	c.not = !c.not
+
+	return c
+}
+
+// operator returns the filter {field: {op: value}}, wrapped in $not when the condition was negated
+func (c Condition) operator(op string, value interface{}) Filter {
+	cond := bson.D{{Key: op, Value: value}}
+
+	if c.not {
+		cond = bson.D{{Key: "$not", Value: cond}}
+	}
+
+	return Filter{bson.D{{Key: c.field, Value: cond}}}
+}
+
+// Eq matches values equal to v
+func (c Condition) Eq(v interface{}) Filter {
+	return c.operator("$eq", v)
+}
+
+// Ne matches values not equal to v, and missing fields
+func (c Condition) Ne(v interface{}) Filter {
+	return c.operator("$ne", v)
+}
+
+// Gt matches values greater than v
+func (c Condition) Gt(v interface{}) Filter {
+	return c.operator("$gt", v)
+}
+
+// Gte matches values greater than or equal to v
+func (c Condition) Gte(v interface{}) Filter {
+	return c.operator("$gte", v)
+}
+
+// Lt matches values less than v
+func (c Condition) Lt(v interface{}) Filter {
+	return c.operator("$lt", v)
+}
+
+// Lte matches values less than or equal to v
+func (c Condition) Lte(v interface{}) Filter {
+	return c.operator("$lte", v)
+}
+
+// In matches values equal to any of the given ones
+func (c Condition) In(values ...interface{}) Filter {
+	return c.operator("$in", bson.A(values))
+}
+
+// Nin matches values equal to none of the given ones, and missing fields
+func (c Condition) Nin(values ...interface{}) Filter {
+	return c.operator("$nin", bson.A(values))
+}
+
+// Exists matches documents that have the field, or that don't have it when exists is false
+func (c Condition) Exists(exists bool) Filter {
+	return c.operator("$exists", exists)
+}
+
+// Type matches values of the given BSON type, by alias like "string" or by number
+func (c Condition) Type(t interface{}) Filter {
+	return c.operator("$type", t)
+}
+
+// Regex matches strings against pattern, with options like "i" for case insensitivity
+func (c Condition) Regex(pattern, options string) Filter {
+	return c.operator("$regex", primitive.Regex{Pattern: pattern, Options: options})
+}
+
+// Mod matches numbers whose division by divisor leaves the given remainder
+func (c Condition) Mod(divisor, remainder int64) Filter {
+	return c.operator("$mod", bson.A{divisor, remainder})
+}
+
+// All matches arrays containing every given value
+func (c Condition) All(values ...interface{}) Filter {
+	return c.operator("$all", bson.A(values))
+}
+
+// ElemMatch matches arrays with at least one element satisfying f
+// For arrays of scalars, build f with an empty field name, like Where("").Gt(5)
+func (c Condition) ElemMatch(f Filter) Filter {
+	d := f.D()
+
+	// conditions on the element itself, instead of on its fields
+	if len(d) == 1 && d[0].Key == "" {
+		return c.operator("$elemMatch", d[0].Value)
+	}
+
+	return c.operator("$elemMatch", d)
+}
+
+// Size matches arrays with exactly n elements
+func (c Condition) Size(n int) Filter {
+	return c.operator("$size", n)
+}
+
+// GeoWithin matches locations entirely inside the given GeoJSON geometry. See Polygon
+func (c Condition) GeoWithin(geometry interface{}) Filter {
+	return c.operator("$geoWithin", bson.D{{Key: "$geometry", Value: geometry}})
+}
+
+// GeoIntersects matches locations that intersect the given GeoJSON geometry
+func (c Condition) GeoIntersects(geometry interface{}) Filter {
+	return c.operator("$geoIntersects", bson.D{{Key: "$geometry", Value: geometry}})
+}
+
+// Near matches locations near the given GeoJSON point, sorted by distance. Distances are in meters; 0 means no limit
+// It requires a geospatial index, and can't be negated
+func (c Condition) Near(point interface{}, maxDistance, minDistance float64) Filter {
+	return c.operator("$near", near(point, maxDistance, minDistance))
+}
+
+// NearSphere works as Near, calculating distances on a sphere
+func (c Condition) NearSphere(point interface{}, maxDistance, minDistance float64) Filter {
+	return c.operator("$nearSphere", near(point, maxDistance, minDistance))
+}
+
+func near(point interface{}, maxDistance, minDistance float64) bson.D {
+	d := bson.D{{Key: "$geometry", Value: point}}
+
+	if maxDistance > 0 {
+		d = append(d, bson.E{Key: "$maxDistance", Value: maxDistance})
+	}
+
+	if minDistance > 0 {
+		d = append(d, bson.E{Key: "$minDistance", Value: minDistance})
+	}
+
+	return d
+}
+
+// Point returns a GeoJSON point. Mind the order: longitude comes first
+func Point(longitude, latitude float64) bson.D {
+	return bson.D{{Key: "type", Value: "Point"}, {Key: "coordinates", Value: bson.A{longitude, latitude}}}
+}
+
+// Polygon returns a GeoJSON polygon with a single ring, given as [longitude, latitude] pairs
+// The ring is closed automatically when the last point differs from the first one
+func Polygon(points ...[2]float64) bson.D {
+	ring := bson.A{}
+
+	for _, p := range points {
+		ring = append(ring, bson.A{p[0], p[1]})
+	}
+
+	if len(points) > 0 && points[0] != points[len(points)-1] {
+		ring = append(ring, bson.A{points[0][0], points[0][1]})
+	}
+
+	return bson.D{{Key: "type", Value: "Polygon"}, {Key: "coordinates", Value: bson.A{ring}}}
+}
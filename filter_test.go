@@ -0,0 +1,71 @@
+package mongohelper
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFilter(t *testing.T) {
+	f := Where("age").Gt(18).And(Where("status").In("a", "b")).And(Where("name").Not().Regex("^x", "i")).Or(Where("vip").Eq(true))
+
+	want := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "$and", Value: bson.A{
+			bson.D{{Key: "age", Value: bson.D{{Key: "$gt", Value: 18}}}},
+			bson.D{{Key: "status", Value: bson.D{{Key: "$in", Value: bson.A{"a", "b"}}}}},
+			bson.D{{Key: "name", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$regex", Value: primitive.Regex{Pattern: "^x", Options: "i"}}}}}}},
+		}}},
+		bson.D{{Key: "vip", Value: bson.D{{Key: "$eq", Value: true}}}},
+	}}}
+
+	if !reflect.DeepEqual(f.D(), want) {
+		t.Errorf("expected %v, got %v", want, f.D())
+	}
+
+	if d := And(Filter{}, Where("a").Exists(true)).D(); len(d) != 1 || d[0].Key != "a" {
+		t.Errorf("expected empty filters to be dropped, got %v", d)
+	}
+
+	if d := (Filter{}).D(); len(d) != 0 {
+		t.Errorf("expected the zero filter to match everything, got %v", d)
+	}
+}
+
+func TestFilter_memoryStore(t *testing.T) {
+	m := MemoryStoreNew()
+
+	docs := []interface{}{
+		bson.M{"name": "Alice", "age": 30, "tags": bson.A{"a", "b"}, "scores": bson.A{bson.M{"v": 5}, bson.M{"v": 9}}},
+		bson.M{"name": "bob", "age": 17, "tags": bson.A{"b"}, "scores": bson.A{bson.M{"v": 1}}},
+		bson.M{"name": "Carol", "age": 45, "tags": bson.A{}, "scores": bson.A{}},
+	}
+
+	if _, err := m.InsertMany(testDB, testCollection, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	filters := map[string]struct {
+		filter Filter
+		want   int64
+	}{
+		"zero":      {Filter{}, 3},
+		"range":     {Where("age").Gte(18).And(Where("age").Lt(40)), 1},
+		"or":        {Where("age").Lt(18).Or(Where("age").Gt(40)), 2},
+		"nor":       {Nor(Where("age").Lt(18), Where("age").Gt(40)), 1},
+		"regex":     {Where("name").Regex("^[ab]", "i"), 2},
+		"not regex": {Where("name").Not().Regex("^[ab]", "i"), 1},
+		"all":       {Where("tags").All("a", "b"), 1},
+		"size":      {Where("tags").Size(0), 1},
+		"elemMatch": {Where("scores").ElemMatch(Where("v").Gt(6)), 1},
+		"scalars":   {Where("tags").ElemMatch(Where("").In("b")), 2},
+		"not":       {Where("age").Not().Gt(20), 1},
+	}
+
+	for name, f := range filters {
+		if n, err := m.CountDocs(testDB, testCollection, f.filter); err != nil || n != f.want {
+			t.Errorf("%s: expected %d documents, got %d ( %v )", name, f.want, n, err)
+		}
+	}
+}
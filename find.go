@@ -2,65 +2,57 @@ package mongohelper
 
 import (
 	"context"
-	"errors"
-	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-//func cursorClose(rs *mongo.Cursor) {
-//	if err := rs.Close(context.TODO()); err != nil {
-//		log.Println(err)
-//	}
-//}
-
 // Find cs wraps the mongo.Database.Collection.Find() method
 // It returns a Cursor over the matching documents in the collection.
 //
 // The filter parameter must be a document containing query operators and can be used to select which documents are
 // included in the result. An empty document (e.g. bson.D{}) should be used to include all documents.
-func (l *Link) Find(database, collection string, filter interface{}, dest interface{}) error {
-	if err := l.linkCheck("link.Find"); err != nil {
-		return err
-	}
+//
+// The opts parameter sorts, limits, skips, projects and hints the query, like Sort("-age"), Limit(10)
+func (l *Link) Find(database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error {
+	return l.FindCtx(context.Background(), database, collection, filter, dest, opts...)
+}
 
+// FindCtx works like Find, but honors the cancellation and deadline of the given context
+// The configured execution timeout still applies as an upper bound, including the eventual reconnection
+func (l *Link) FindCtx(ctx context.Context, database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error {
 	if dest == nil {
-		return fmt.Errorf(`given "dest" is null`)
+		return &OpError{Op: "Find", Database: database, Collection: collection, Kind: ErrNilDestination}
 	}
 
 	if filter == nil {
 		filter = bson.M{}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), l.execTimeout())
-
-	defer cancel()
+	op := Operation{Kind: OpFind, Database: database, Collection: collection, Filter: filter, Dest: dest, Options: findOptions(opts)}
 
-	rs, err := l.client.Database(database).Collection(collection).Find(ctx, filter, options.Find())
+	_, err := l.run(ctx, &op, func(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error) {
+		rs, err := op.cursor(ctx, client)
 
-	if err != nil {
-		// If not connected, try once again, reconnecting. otherwise, just return/leave
-		if !errors.Is(err, mongo.ErrClientDisconnected) {
-			return err
+		if err != nil {
+			return nil, err
 		}
 
-		if err := l.connect(); err != nil {
-			return err
-		}
+		// All() closes the cursor when it's done
+		return nil, rs.All(ctx, op.Dest)
+	})
 
-		ctx2, cancel2 := context.WithTimeout(context.Background(), l.execTimeout())
+	return err
+}
 
-		defer cancel2()
+// cursor runs the query described by op, that has a filter and find options, returning the cursor over its results
+func (op *Operation) cursor(ctx context.Context, client *mongo.Client) (*mongo.Cursor, error) {
+	coll := client.Database(op.Database).Collection(op.Collection)
 
-		rs, err = l.client.Database(database).Collection(collection).Find(ctx2, filter, options.Find())
+	if op.Options.AllowDiskUse {
+		p, opts := op.Options.aggregate(op.Filter)
 
-		if err != nil {
-			return err
-		}
+		return coll.Aggregate(ctx, p, opts)
 	}
 
-	//defer cursorClose(rs)
-
-	return rs.All(context.TODO(), dest)
+	return coll.Find(ctx, op.Filter, op.Options.find())
 }
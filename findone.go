@@ -2,11 +2,8 @@ package mongohelper
 
 import (
 	"context"
-	"errors"
-	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // FindOne wraps the mongo.Database.Collection.FindOne() method
@@ -15,49 +12,28 @@ import (
 // The filter parameter must be a document containing query operators and can be used to select the document to be
 // returned. If the filter does not match any documents, a SingleResult with an error set to
 // ErrNoDocuments will be returned. If the filter matches multiple documents, one will be selected from the matched set.
-func (l *Link) FindOne(database, collection string, filter interface{}, dest interface{}) error {
-	if err := l.linkCheck("link.FindOne"); err != nil {
-		return err
-	}
+//
+// The opts parameter sorts, skips, projects and hints the query, like Sort("-createdAt") to get the newest document
+func (l *Link) FindOne(database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error {
+	return l.FindOneCtx(context.Background(), database, collection, filter, dest, opts...)
+}
 
+// FindOneCtx works like FindOne, but honors the cancellation and deadline of the given context
+// The configured execution timeout still applies as an upper bound, including the eventual reconnection
+func (l *Link) FindOneCtx(ctx context.Context, database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error {
 	if dest == nil {
-		return fmt.Errorf(`given "dest" is null`)
+		return &OpError{Op: "FindOne", Database: database, Collection: collection, Kind: ErrNilDestination}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), l.execTimeout())
-
-	defer cancel()
-
 	if filter == nil {
 		filter = bson.M{}
 	}
 
-	rs := l.client.Database(database).Collection(collection).FindOne(ctx, filter, options.FindOne())
-
-	if err := rs.Err(); err != nil {
-		// If not connected, try once again, reconnecting. otherwise, just return/leave
-		if !errors.Is(err, mongo.ErrClientDisconnected) {
-			return err
-		}
-
-		if err := l.connect(); err != nil {
-			return err
-		}
+	op := Operation{Kind: OpFindOne, Database: database, Collection: collection, Filter: filter, Dest: dest, Options: findOptions(opts)}
 
-		ctx2, cancel2 := context.WithTimeout(context.Background(), l.execTimeout())
-
-		defer cancel2()
-
-		rs = l.client.Database(database).Collection(collection).FindOne(ctx2, filter, options.FindOne())
-
-		if err := rs.Err(); err != nil {
-			return err
-		}
-	}
-
-	if err := rs.Decode(dest); err != nil {
-		return err
-	}
+	_, err := l.run(ctx, &op, func(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error) {
+		return nil, client.Database(op.Database).Collection(op.Collection).FindOne(ctx, op.Filter, op.Options.findOne()).Decode(op.Dest)
+	})
 
-	return nil
+	return err
 }
@@ -0,0 +1,251 @@
+package mongohelper
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FindOptions tunes Find, FindOne and CountDocs. Zero values mean the server defaults
+// Each operation uses the options that make sense for it: CountDocs ignores Sort, Projection, BatchSize and
+// AllowDiskUse, and FindOne ignores Limit, BatchSize and AllowDiskUse
+type FindOptions struct {
+	// Sort is the sort document, like bson.D{{Key: "age", Value: -1}}
+	Sort bson.D
+	// Limit is the maximum number of documents returned or counted
+	Limit int64
+	// Skip is the number of documents to skip before returning or counting
+	Skip int64
+	// Projection selects the returned fields, like bson.D{{Key: "name", Value: 1}}
+	Projection interface{}
+	// Hint is the index to use, by name or by specification
+	Hint interface{}
+	// Collation sets language specific rules for string comparison
+	Collation *options.Collation
+	// MaxTime limits the server side execution time
+	MaxTime time.Duration
+	// BatchSize is the number of documents in each batch sent by the server
+	BatchSize int32
+	// AllowDiskUse lets the server use temporary files for large sorts
+	// The driver in use can't send it along with find, so Find runs as an equivalent aggregation when it's set
+	AllowDiskUse bool
+}
+
+// FindOption sets one of the FindOptions
+type FindOption func(*FindOptions)
+
+// Sort orders the documents by the given fields. A field prefixed by "-" sorts in descending order
+func Sort(fields ...string) FindOption {
+	return func(o *FindOptions) {
+		o.Sort = append(o.Sort, sortDoc(fields)...)
+	}
+}
+
+// Limit returns or counts at most n documents
+func Limit(n int64) FindOption {
+	return func(o *FindOptions) {
+		o.Limit = n
+	}
+}
+
+// Skip skips the first n documents
+func Skip(n int64) FindOption {
+	return func(o *FindOptions) {
+		o.Skip = n
+	}
+}
+
+// Projection selects the returned fields, like bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 0}}
+func Projection(projection interface{}) FindOption {
+	return func(o *FindOptions) {
+		o.Projection = projection
+	}
+}
+
+// Hint forces the index to use, given by name or by specification
+func Hint(index interface{}) FindOption {
+	return func(o *FindOptions) {
+		o.Hint = index
+	}
+}
+
+// Collation sets language specific rules for string comparison
+func Collation(c *options.Collation) FindOption {
+	return func(o *FindOptions) {
+		o.Collation = c
+	}
+}
+
+// MaxTime limits the server side execution time. The configured execution timeout still applies on client side
+func MaxTime(d time.Duration) FindOption {
+	return func(o *FindOptions) {
+		o.MaxTime = d
+	}
+}
+
+// BatchSize sets the number of documents in each batch sent by the server
+func BatchSize(n int32) FindOption {
+	return func(o *FindOptions) {
+		o.BatchSize = n
+	}
+}
+
+// AllowDiskUse lets the server use temporary files for large sorts
+func AllowDiskUse() FindOption {
+	return func(o *FindOptions) {
+		o.AllowDiskUse = true
+	}
+}
+
+// findOptions applies opts over the zero FindOptions
+func findOptions(opts []FindOption) *FindOptions {
+	o := &FindOptions{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// find returns the driver options of Find
+func (o *FindOptions) find() *options.FindOptions {
+	f := options.Find()
+
+	if len(o.Sort) > 0 {
+		f.SetSort(o.Sort)
+	}
+
+	if o.Limit != 0 {
+		f.SetLimit(o.Limit)
+	}
+
+	if o.Skip > 0 {
+		f.SetSkip(o.Skip)
+	}
+
+	if o.Projection != nil {
+		f.SetProjection(o.Projection)
+	}
+
+	if o.Hint != nil {
+		f.SetHint(o.Hint)
+	}
+
+	if o.Collation != nil {
+		f.SetCollation(o.Collation)
+	}
+
+	if o.MaxTime > 0 {
+		f.SetMaxTime(o.MaxTime)
+	}
+
+	if o.BatchSize > 0 {
+		f.SetBatchSize(o.BatchSize)
+	}
+
+	return f
+}
+
+// findOne returns the driver options of FindOne
+func (o *FindOptions) findOne() *options.FindOneOptions {
+	f := options.FindOne()
+
+	if len(o.Sort) > 0 {
+		f.SetSort(o.Sort)
+	}
+
+	if o.Skip > 0 {
+		f.SetSkip(o.Skip)
+	}
+
+	if o.Projection != nil {
+		f.SetProjection(o.Projection)
+	}
+
+	if o.Hint != nil {
+		f.SetHint(o.Hint)
+	}
+
+	if o.Collation != nil {
+		f.SetCollation(o.Collation)
+	}
+
+	if o.MaxTime > 0 {
+		f.SetMaxTime(o.MaxTime)
+	}
+
+	return f
+}
+
+// count returns the driver options of CountDocs
+func (o *FindOptions) count() *options.CountOptions {
+	c := options.Count()
+
+	if o.Limit > 0 {
+		c.SetLimit(o.Limit)
+	}
+
+	if o.Skip > 0 {
+		c.SetSkip(o.Skip)
+	}
+
+	if o.Hint != nil {
+		c.SetHint(o.Hint)
+	}
+
+	if o.Collation != nil {
+		c.SetCollation(o.Collation)
+	}
+
+	if o.MaxTime > 0 {
+		c.SetMaxTime(o.MaxTime)
+	}
+
+	return c
+}
+
+// aggregate returns the pipeline and driver options of the aggregation equivalent to Find with filter
+func (o *FindOptions) aggregate(filter interface{}) (Pipeline, *options.AggregateOptions) {
+	p := PipelineNew().Match(filter)
+
+	if len(o.Sort) > 0 {
+		p = p.Stage("$sort", o.Sort)
+	}
+
+	if o.Skip > 0 {
+		p = p.Skip(o.Skip)
+	}
+
+	// negative limits mean a single batch on find; the aggregation just takes their absolute value
+	if o.Limit > 0 {
+		p = p.Limit(o.Limit)
+	} else if o.Limit < 0 {
+		p = p.Limit(-o.Limit)
+	}
+
+	if o.Projection != nil {
+		p = p.Project(o.Projection)
+	}
+
+	a := options.Aggregate().SetAllowDiskUse(o.AllowDiskUse)
+
+	if o.Hint != nil {
+		a.SetHint(o.Hint)
+	}
+
+	if o.Collation != nil {
+		a.SetCollation(o.Collation)
+	}
+
+	if o.MaxTime > 0 {
+		a.SetMaxTime(o.MaxTime)
+	}
+
+	if o.BatchSize > 0 {
+		a.SetBatchSize(o.BatchSize)
+	}
+
+	return p, a
+}
@@ -0,0 +1,94 @@
+package mongohelper
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFindOptions(t *testing.T) {
+	o := findOptions([]FindOption{Sort("-age", "name"), Limit(10), Skip(5), Projection(bson.D{{Key: "name", Value: 1}}), Hint("age_1"), MaxTime(time.Second), BatchSize(50)})
+
+	f := o.find()
+
+	if !reflect.DeepEqual(f.Sort, bson.D{{Key: "age", Value: -1}, {Key: "name", Value: 1}}) || *f.Limit != 10 || *f.Skip != 5 || f.Hint != "age_1" || *f.MaxTime != time.Second || *f.BatchSize != 50 || f.Projection == nil {
+		t.Errorf("unexpected find options %+v", f)
+	}
+
+	if c := o.count(); *c.Limit != 10 || *c.Skip != 5 || c.Hint != "age_1" {
+		t.Errorf("unexpected count options %+v", c)
+	}
+
+	if f := o.findOne(); *f.Skip != 5 || f.Sort == nil {
+		t.Errorf("unexpected find one options %+v", f)
+	}
+
+	p, a := findOptions([]FindOption{AllowDiskUse(), Sort("age"), Limit(3)}).aggregate(bson.M{"x": 1})
+
+	if len(p) != 3 || p[0][0].Key != "$match" || p[1][0].Key != "$sort" || p[2][0].Key != "$limit" || !*a.AllowDiskUse {
+		t.Errorf("unexpected aggregation %v %+v", p, a)
+	}
+}
+
+func TestFindOptions_link(t *testing.T) {
+	var (
+		l    Link
+		seen []*FindOptions
+	)
+
+	l.Use(func(next OpFunc) OpFunc {
+		return func(ctx context.Context, op *Operation) (*OpResult, error) {
+			seen = append(seen, op.Options)
+
+			return next(ctx, op)
+		}
+	})
+
+	var dest []bson.M
+
+	_ = l.Find(testDB, testCollection, nil, &dest, Limit(2))
+	_ = l.FindOne(testDB, testCollection, nil, &bson.M{})
+	_, _ = l.CountDocs(testDB, testCollection, bson.M{}, Skip(1))
+
+	if len(seen) != 3 || seen[0].Limit != 2 || seen[1] == nil || seen[2].Skip != 1 {
+		t.Errorf("expected the options to reach the interceptors, got %v", seen)
+	}
+}
+
+func TestFindOptions_memoryStore(t *testing.T) {
+	m := MemoryStoreNew()
+
+	for i := 0; i < 10; i++ {
+		if _, err := m.InsertOne(testDB, testCollection, bson.M{"_id": i, "name": fmt.Sprintf("doc %d", i), "group": i % 2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var docs []bson.M
+
+	if err := m.Find(testDB, testCollection, nil, &docs, Sort("group", "-_id"), Skip(1), Limit(3), Projection(bson.M{"name": 0})); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(docs) != 3 || docs[0]["_id"] != int32(6) || docs[2]["_id"] != int32(2) || docs[0]["name"] != nil || docs[0]["group"] != int32(0) {
+		t.Errorf("unexpected documents %v", docs)
+	}
+
+	var doc bson.M
+
+	if err := m.FindOne(testDB, testCollection, bson.M{"group": 1}, &doc, Sort("-_id"), Projection(bson.M{"name": 1, "_id": 0})); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc) != 1 || doc["name"] != "doc 9" {
+		t.Errorf("unexpected document %v", doc)
+	}
+
+	if n, err := m.CountDocs(testDB, testCollection, bson.M{}, Skip(8), Limit(5)); err != nil || n != 2 {
+		t.Errorf("expected 2 documents, got %d ( %v )", n, err)
+	}
+}
@@ -0,0 +1,449 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IndexSpec declares an index of a collection
+type IndexSpec struct {
+	// Keys are the indexed fields, in order, like IndexKeys("tenant", "-createdAt") or bson.D{{Key: "title", Value: "text"}}
+	Keys bson.D
+	// Name defaults to the server convention, like tenant_1_createdAt_-1
+	Name   string
+	Unique bool
+	Sparse bool
+	// PartialFilter restricts the index to the documents matching it, like Where("deletedAt").Exists(false)
+	PartialFilter interface{}
+	// TTL removes the documents once the indexed date is older than it, like ExpireAfter(24 * time.Hour)
+	// It must be a whole number of seconds, and 0 expires the documents at the indexed date. nil means no TTL
+	TTL       *time.Duration
+	Collation *options.Collation
+}
+
+// ExpireAfter returns a TTL of d for IndexSpec
+func ExpireAfter(d time.Duration) *time.Duration {
+	return &d
+}
+
+// IndexKeys returns the keys of an index on the given fields, ascending or, with a "-" prefix, descending
+func IndexKeys(fields ...string) bson.D {
+	return sortDoc(fields)
+}
+
+// name returns the given name, or the one the server would give
+func (s IndexSpec) name() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	a := make([]string, len(s.Keys))
+
+	for i, k := range s.Keys {
+		a[i] = fmt.Sprintf("%s_%v", k.Key, k.Value)
+	}
+
+	return strings.Join(a, "_")
+}
+
+// model returns the driver model of the index
+func (s IndexSpec) model() mongo.IndexModel {
+	o := options.Index().SetName(s.name())
+
+	if s.Unique {
+		o.SetUnique(true)
+	}
+
+	if s.Sparse {
+		o.SetSparse(true)
+	}
+
+	if s.PartialFilter != nil {
+		o.SetPartialFilterExpression(s.PartialFilter)
+	}
+
+	if s.TTL != nil {
+		o.SetExpireAfterSeconds(int32(*s.TTL / time.Second))
+	}
+
+	if s.Collation != nil {
+		o.SetCollation(s.Collation)
+	}
+
+	return mongo.IndexModel{Keys: s.Keys, Options: o}
+}
+
+// validate checks what the server would reject anyway, before anything is changed
+func (s IndexSpec) validate() error {
+	switch {
+	case len(s.Keys) == 0:
+		return errors.New("no keys")
+	case s.TTL != nil && (*s.TTL < 0 || *s.TTL%time.Second != 0):
+		return fmt.Errorf("TTL %s isn't a whole number of seconds", *s.TTL)
+	case s.TTL != nil && *s.TTL/time.Second > math.MaxInt32:
+		return fmt.Errorf("TTL %s is longer than %d seconds", *s.TTL, math.MaxInt32)
+	case s.name() == "_id_":
+		return errors.New("the _id index can't be declared")
+	}
+
+	return nil
+}
+
+// indexInfo is an existing index, as listIndexes describes it
+type indexInfo struct {
+	Name                    string      `bson:"name"`
+	Key                     bson.D      `bson:"key"`
+	Unique                  bool        `bson:"unique"`
+	Sparse                  bool        `bson:"sparse"`
+	PartialFilterExpression bson.Raw    `bson:"partialFilterExpression"`
+	ExpireAfterSeconds      interface{} `bson:"expireAfterSeconds"`
+	Collation               bson.Raw    `bson:"collation"`
+}
+
+// diff returns how x differs from s, or an empty string if they're the same index
+func (s IndexSpec) diff(x indexInfo) string {
+	keys, _ := toDoc(s.Keys)
+
+	if !sameKeys(keys, x.Key) {
+		return "keys differ"
+	}
+
+	if s.Unique != x.Unique {
+		return "unique differs"
+	}
+
+	if s.Sparse != x.Sparse {
+		return "sparse differs"
+	}
+
+	if ttl, ok := toFloat(x.ExpireAfterSeconds); ok != (s.TTL != nil) || ok && ttl != float64(*s.TTL/time.Second) {
+		return "TTL differs"
+	}
+
+	filter, err := toDoc(s.PartialFilter)
+
+	if err != nil {
+		return "partial filter differs"
+	}
+
+	existing, _ := toDoc(x.PartialFilterExpression)
+
+	if !sameValue(filter, existing) {
+		return "partial filter differs"
+	}
+
+	// the server fills in the collation defaults, so only the declared fields are compared
+	collation, _ := toDoc(x.Collation)
+
+	if s.Collation == nil {
+		if len(collation) > 0 {
+			return "collation differs"
+		}
+
+		return ""
+	}
+
+	declared, _ := toDoc(s.Collation.ToDocument())
+
+	for _, e := range declared {
+		if v, ok := docGet(collation, e.Key); !ok || !equal(e.Value, v) {
+			return "collation differs"
+		}
+	}
+
+	return ""
+}
+
+// sameKeys compares index keys, that are ordered
+func sameKeys(a, b bson.D) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Key != b[i].Key || !equal(a[i].Value, b[i].Value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sameValue compares bson values, ignoring the order of the fields of documents, and numbers by their numeric value
+func sameValue(a, b interface{}) bool {
+	switch x := a.(type) {
+	case bson.D:
+		y, ok := b.(bson.D)
+
+		if !ok || len(x) != len(y) {
+			return false
+		}
+
+		for _, e := range x {
+			if v, ok := docGet(y, e.Key); !ok || !sameValue(e.Value, v) {
+				return false
+			}
+		}
+
+		return true
+	case bson.A:
+		y, ok := b.(bson.A)
+
+		if !ok || len(x) != len(y) {
+			return false
+		}
+
+		for i := range x {
+			if !sameValue(x[i], y[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return equal(a, b)
+}
+
+// IndexAction is a change EnsureIndexes makes to the indexes of a collection
+type IndexAction string
+
+// Index actions
+const (
+	// IndexCreate creates a declared index that doesn't exist
+	IndexCreate IndexAction = "create"
+	// IndexReplace drops an existing index that differs from its declaration, and creates it again
+	IndexReplace IndexAction = "replace"
+	// IndexDrop drops an existing index that isn't declared
+	IndexDrop IndexAction = "drop"
+)
+
+// IndexChange is a change EnsureIndexes makes, or would make on a dry run
+type IndexChange struct {
+	Action IndexAction
+	Name   string
+	// Reason tells why an index is replaced, like "unique differs"
+	Reason string
+	// Spec is the declared index. It's nil for IndexDrop
+	Spec *IndexSpec
+}
+
+// IndexSyncOptions tunes EnsureIndexes
+type IndexSyncOptions struct {
+	// DropExtra drops the existing indexes that aren't declared. The _id index is never dropped
+	DropExtra bool
+	// DryRun only returns the plan, changing nothing
+	DryRun bool
+}
+
+// IndexReport is the outcome of EnsureIndexes
+type IndexReport struct {
+	// Changes are in the order they're applied
+	Changes []IndexChange
+	// Unchanged are the declared indexes that already exist as declared
+	Unchanged []string
+	// Extra are the existing indexes that aren't declared and weren't dropped, for lack of DropExtra
+	Extra []string
+	// Applied is false on dry runs, or when there was nothing to change
+	Applied bool
+}
+
+// EnsureIndexes makes the indexes of a collection match specs, comparing them with the existing ones
+// Missing indexes are created, and existing ones that differ from their declaration are dropped and created again,
+// as indexes can't be modified. An existing index matches a spec by name, or else by keys
+// It returns what was changed, or the plan alone with opts.DryRun. On failure, the report lists the changes planned
+func (l *Link) EnsureIndexes(database, collection string, specs []IndexSpec, opts IndexSyncOptions) (*IndexReport, error) {
+	return l.EnsureIndexesCtx(context.Background(), database, collection, specs, opts)
+}
+
+// EnsureIndexesCtx works like EnsureIndexes, but honors the cancellation and deadline of the given context
+func (l *Link) EnsureIndexesCtx(ctx context.Context, database, collection string, specs []IndexSpec, opts IndexSyncOptions) (*IndexReport, error) {
+	names := make(map[string]bool, len(specs))
+
+	for i, s := range specs {
+		err := s.validate()
+
+		if err == nil && names[s.name()] {
+			err = errors.New("duplicated name")
+		}
+
+		if err != nil {
+			return nil, &OpError{Op: "EnsureIndexes", Database: database, Collection: collection, Kind: ErrInvalidIndexSpec, Err: fmt.Errorf("index %d %q: %w", i, s.name(), err)}
+		}
+
+		names[s.name()] = true
+	}
+
+	existing, err := l.listIndexes(ctx, database, collection)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rp := indexPlan(existing, specs, opts.DropExtra)
+
+	if opts.DryRun || len(rp.Changes) == 0 {
+		return rp, nil
+	}
+
+	var (
+		drop   []IndexSpec
+		create []IndexSpec
+	)
+
+	for _, c := range rp.Changes {
+		if c.Action != IndexCreate {
+			drop = append(drop, IndexSpec{Name: c.Name})
+		}
+
+		if c.Action != IndexDrop {
+			create = append(create, *c.Spec)
+		}
+	}
+
+	// replaced indexes are dropped first, as they may keep their names
+	if len(drop) > 0 {
+		op := Operation{Kind: OpDropIndexes, Database: database, Collection: collection, Indexes: drop}
+
+		if _, err := l.run(ctx, &op, dropIndexes); err != nil {
+			return rp, err
+		}
+	}
+
+	if len(create) > 0 {
+		op := Operation{Kind: OpCreateIndexes, Database: database, Collection: collection, Indexes: create}
+
+		if _, err := l.run(ctx, &op, createIndexes); err != nil {
+			return rp, err
+		}
+	}
+
+	rp.Applied = true
+
+	return rp, nil
+}
+
+// indexPlan returns the changes that make existing match specs
+func indexPlan(existing []indexInfo, specs []IndexSpec, dropExtra bool) *IndexReport {
+	rp := &IndexReport{}
+
+	matched := make(map[string]bool, len(existing))
+
+	for i := range specs {
+		s := &specs[i]
+
+		x, ok := findIndex(existing, *s)
+
+		if !ok {
+			rp.Changes = append(rp.Changes, IndexChange{Action: IndexCreate, Name: s.name(), Spec: s})
+
+			continue
+		}
+
+		matched[x.Name] = true
+
+		// an index found by keys under another name is replaced, as the server rejects two indexes on the same keys
+		reason := s.diff(x)
+
+		if reason == "" && x.Name != s.name() {
+			reason = "name differs"
+		}
+
+		if reason == "" {
+			rp.Unchanged = append(rp.Unchanged, s.name())
+
+			continue
+		}
+
+		rp.Changes = append(rp.Changes, IndexChange{Action: IndexReplace, Name: x.Name, Reason: reason, Spec: s})
+	}
+
+	for _, x := range existing {
+		if matched[x.Name] || x.Name == "_id_" {
+			continue
+		}
+
+		if dropExtra {
+			rp.Changes = append(rp.Changes, IndexChange{Action: IndexDrop, Name: x.Name})
+		} else {
+			rp.Extra = append(rp.Extra, x.Name)
+		}
+	}
+
+	return rp
+}
+
+// findIndex returns the existing index of s: the one with the same name, or else the one with the same keys
+func findIndex(existing []indexInfo, s IndexSpec) (indexInfo, bool) {
+	for _, x := range existing {
+		if x.Name == s.name() {
+			return x, true
+		}
+	}
+
+	keys, _ := toDoc(s.Keys)
+
+	for _, x := range existing {
+		if x.Name != "_id_" && sameKeys(keys, x.Key) {
+			return x, true
+		}
+	}
+
+	return indexInfo{}, false
+}
+
+// listIndexes returns the indexes of the given collection, that are none if it doesn't exist
+func (l *Link) listIndexes(ctx context.Context, database, collection string) ([]indexInfo, error) {
+	op := Operation{Kind: OpListIndexes, Database: database, Collection: collection}
+
+	var a []indexInfo
+
+	_, err := l.run(ctx, &op, func(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error) {
+		rs, err := client.Database(op.Database).Collection(op.Collection).Indexes().List(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		a = nil
+
+		return nil, rs.All(ctx, &a)
+	})
+
+	return a, err
+}
+
+func createIndexes(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error) {
+	models := make([]mongo.IndexModel, len(op.Indexes))
+
+	for i, s := range op.Indexes {
+		models[i] = s.model()
+	}
+
+	_, err := client.Database(op.Database).Collection(op.Collection).Indexes().CreateMany(ctx, models)
+
+	return nil, err
+}
+
+func dropIndexes(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error) {
+	for _, s := range op.Indexes {
+		_, err := client.Database(op.Database).Collection(op.Collection).Indexes().DropOne(ctx, s.Name)
+
+		// IndexNotFound: already dropped by a previous attempt
+		var ce mongo.CommandError
+
+		if err != nil && !(errors.As(err, &ce) && ce.Code == 27) {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,140 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestIndexPlan(t *testing.T) {
+	existing := []indexInfo{
+		{Name: "_id_", Key: bson.D{{Key: "_id", Value: int32(1)}}},
+		{Name: "email_1", Key: bson.D{{Key: "email", Value: int32(1)}}, Unique: true, Collation: mustRaw(bson.M{"locale": "en", "strength": int32(2), "caseLevel": false})},
+		{Name: "tenant_1_createdAt_-1", Key: bson.D{{Key: "tenant", Value: int32(1)}, {Key: "createdAt", Value: int32(-1)}}},
+		{Name: "expires", Key: bson.D{{Key: "expiresAt", Value: int32(1)}}, ExpireAfterSeconds: int32(3600)},
+		{Name: "active_name", Key: bson.D{{Key: "name", Value: int32(1)}}, PartialFilterExpression: mustRaw(bson.M{"active": true})},
+		{Name: "legacy", Key: bson.D{{Key: "legacy", Value: int32(1)}}},
+		{Name: "deleteAt_1", Key: bson.D{{Key: "deleteAt", Value: int32(1)}}, ExpireAfterSeconds: int32(0)},
+	}
+
+	specs := []IndexSpec{
+		{Keys: IndexKeys("email"), Unique: true, Collation: &options.Collation{Locale: "en", Strength: 2}},
+		{Keys: IndexKeys("tenant", "-createdAt"), Unique: true},
+		{Keys: IndexKeys("expiresAt"), Name: "expires", TTL: ExpireAfter(time.Hour)},
+		{Keys: IndexKeys("deleteAt"), TTL: ExpireAfter(0)},
+		{Keys: IndexKeys("name"), Name: "by_name", PartialFilter: bson.M{"active": true}},
+		{Keys: IndexKeys("sku"), Sparse: true},
+	}
+
+	rp := indexPlan(existing, specs, false)
+
+	want := []IndexChange{
+		{Action: IndexReplace, Name: "tenant_1_createdAt_-1", Reason: "unique differs"},
+		{Action: IndexReplace, Name: "active_name", Reason: "name differs"},
+		{Action: IndexCreate, Name: "sku_1"},
+	}
+
+	if len(rp.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), rp.Changes)
+	}
+
+	for i, c := range want {
+		if got := rp.Changes[i]; got.Action != c.Action || got.Name != c.Name || got.Reason != c.Reason || got.Spec != &specs[[]int{1, 4, 5}[i]] {
+			t.Errorf("expected %+v, got %+v", c, got)
+		}
+	}
+
+	if len(rp.Unchanged) != 3 || rp.Unchanged[0] != "email_1" || rp.Unchanged[1] != "expires" || rp.Unchanged[2] != "deleteAt_1" {
+		t.Errorf("expected 3 unchanged indexes, got %v", rp.Unchanged)
+	}
+
+	if len(rp.Extra) != 1 || rp.Extra[0] != "legacy" {
+		t.Errorf("expected the legacy index to be extra, got %v", rp.Extra)
+	}
+
+	rp = indexPlan(existing, specs, true)
+
+	if c := rp.Changes[len(rp.Changes)-1]; c.Action != IndexDrop || c.Name != "legacy" || len(rp.Extra) != 0 {
+		t.Errorf("expected the legacy index to be dropped, got %+v", rp.Changes)
+	}
+
+	specs[2].TTL = ExpireAfter(2 * time.Hour)
+
+	if rp := indexPlan(existing, specs[2:3], false); len(rp.Changes) != 1 || rp.Changes[0].Reason != "TTL differs" {
+		t.Errorf("expected the TTL index to be replaced, got %+v", rp.Changes)
+	}
+
+	specs[3].TTL = nil
+
+	if rp := indexPlan(existing, specs[3:4], false); len(rp.Changes) != 1 || rp.Changes[0].Reason != "TTL differs" {
+		t.Errorf("expected the index to lose its TTL, got %+v", rp.Changes)
+	}
+
+	if o := (IndexSpec{Keys: IndexKeys("at"), TTL: ExpireAfter(0)}).model().Options; o.ExpireAfterSeconds == nil || *o.ExpireAfterSeconds != 0 {
+		t.Errorf("expected a TTL of 0 seconds, got %v", o.ExpireAfterSeconds)
+	}
+}
+
+func TestLink_EnsureIndexes(t *testing.T) {
+	var (
+		l       Link
+		created []IndexSpec
+		dropped []IndexSpec
+	)
+
+	l.Use(func(next OpFunc) OpFunc {
+		return func(ctx context.Context, op *Operation) (*OpResult, error) {
+			switch op.Kind {
+			case OpCreateIndexes:
+				created = append(created, op.Indexes...)
+			case OpDropIndexes:
+				dropped = append(dropped, op.Indexes...)
+			}
+
+			return nil, nil
+		}
+	})
+
+	specs := []IndexSpec{{Keys: IndexKeys("email"), Unique: true}, {Keys: IndexKeys("-createdAt"), TTL: ExpireAfter(24 * time.Hour)}}
+
+	rp, err := l.EnsureIndexes(testDB, testCollection, specs, IndexSyncOptions{DryRun: true})
+
+	if err != nil || rp.Applied || len(rp.Changes) != 2 || len(created) != 0 {
+		t.Fatalf("expected a plan with 2 indexes to create, got %+v, %v ( %v )", rp, created, err)
+	}
+
+	rp, err = l.EnsureIndexes(testDB, testCollection, specs, IndexSyncOptions{})
+
+	if err != nil || !rp.Applied || len(created) != 2 || len(dropped) != 0 || created[1].name() != "createdAt_-1" {
+		t.Errorf("expected 2 created indexes, got %+v, %v ( %v )", rp, created, err)
+	}
+
+	invalid := map[string][]IndexSpec{
+		"no keys":   {{Name: "empty"}},
+		"ttl":       {{Keys: IndexKeys("at"), TTL: ExpireAfter(1500 * time.Millisecond)}},
+		"ttl range": {{Keys: IndexKeys("at"), TTL: ExpireAfter((math.MaxInt32 + 1) * time.Second)}},
+		"id":        {{Keys: IndexKeys("_id"), Name: "_id_"}},
+		"duplicate": {{Keys: IndexKeys("a")}, {Keys: IndexKeys("b"), Name: "a_1"}},
+	}
+
+	for name, specs := range invalid {
+		if _, err := l.EnsureIndexes(testDB, testCollection, specs, IndexSyncOptions{}); !errors.Is(err, ErrInvalidIndexSpec) {
+			t.Errorf("%s: expected ErrInvalidIndexSpec, got %v", name, err)
+		}
+	}
+}
+
+func mustRaw(v interface{}) []byte {
+	b, err := bson.Marshal(v)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
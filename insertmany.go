@@ -2,7 +2,6 @@ package mongohelper
 
 import (
 	"context"
-	"errors"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -11,33 +10,26 @@ import (
 // InsertMany wraps the mongo.Database.Collection.InsertMany() method
 // It returns an array with generated ObjectIDs and an error
 func (l *Link) InsertMany(database, collection string, document []interface{}) ([]string, error) {
-	if err := l.linkCheck("link.InsertMany"); err != nil {
-		return []string{}, err
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), l.execTimeout())
-
-	defer cancel()
+	return l.InsertManyCtx(context.Background(), database, collection, document)
+}
 
-	rs, err := l.client.Database(database).Collection(collection).InsertMany(ctx, document, options.InsertMany())
+// InsertManyCtx works like InsertMany, but honors the cancellation and deadline of the given context
+// The configured execution timeout still applies as an upper bound, including the eventual reconnection
+func (l *Link) InsertManyCtx(ctx context.Context, database, collection string, document []interface{}) ([]string, error) {
+	op := Operation{Kind: OpInsertMany, Database: database, Collection: collection, Documents: document}
 
-	if err != nil {
-		// If not connected, try once again
-		if errors.Is(err, mongo.ErrClientDisconnected) {
-			if err = l.connect(); err != nil {
-				return []string{}, err
-			}
+	rs, err := l.run(ctx, &op, func(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error) {
+		rs, err := client.Database(op.Database).Collection(op.Collection).InsertMany(ctx, op.Documents, options.InsertMany())
 
-			ctx2, cancel2 := context.WithTimeout(context.Background(), l.execTimeout())
+		if err != nil {
+			return nil, err
+		}
 
-			defer cancel2()
+		return &OpResult{InsertedIDs: rs.InsertedIDs}, nil
+	})
 
-			if rs, err = l.client.Database(database).Collection(collection).InsertMany(ctx2, document, options.InsertMany()); err != nil {
-				return []string{}, err
-			}
-		} else {
-			return []string{}, err
-		}
+	if err != nil {
+		return []string{}, err
 	}
 
 	var oidHex []string
@@ -2,7 +2,7 @@ package mongohelper
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -11,39 +11,38 @@ import (
 // InsertOne wraps the mongo.Database.Collection.InsertOne() method
 // It returns the generated ObjectId and an error
 func (l *Link) InsertOne(database, collection string, document interface{}) (string, error) {
-	if err := l.linkCheck("link.InsertOne"); err != nil {
-		return "", err
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), l.execTimeout())
+	return l.InsertOneCtx(context.Background(), database, collection, document)
+}
 
-	defer cancel()
+// InsertOneCtx works like InsertOne, but honors the cancellation and deadline of the given context
+// The configured execution timeout still applies as an upper bound, including the eventual reconnection
+func (l *Link) InsertOneCtx(ctx context.Context, database, collection string, document interface{}) (string, error) {
+	op := Operation{Kind: OpInsertOne, Database: database, Collection: collection, Documents: []interface{}{document}}
 
-	rs, err := l.client.Database(database).Collection(collection).InsertOne(ctx, document, options.InsertOne())
+	rs, err := l.run(ctx, &op, func(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error) {
+		if len(op.Documents) != 1 {
+			return nil, fmt.Errorf("InsertOne needs exactly one document, got %d", len(op.Documents))
+		}
 
-	if err != nil {
-		// If not connected, try once again
-		if errors.Is(err, mongo.ErrClientDisconnected) {
-			if err = l.connect(); err != nil {
-				return ``, err
-			}
+		rs, err := client.Database(op.Database).Collection(op.Collection).InsertOne(ctx, op.Documents[0], options.InsertOne())
 
-			ctx2, cancel2 := context.WithTimeout(context.Background(), l.execTimeout())
+		if err != nil {
+			return nil, err
+		}
 
-			defer cancel2()
+		return &OpResult{InsertedIDs: []interface{}{rs.InsertedID}}, nil
+	})
 
-			if rs, err = l.client.Database(database).Collection(collection).InsertOne(ctx2, document, options.InsertOne()); err != nil {
-				return ``, err
-			}
-		} else {
-			return ``, err
-		}
+	if err != nil {
+		return ``, err
 	}
 
 	oidHex := ""
 
-	if oid, ok := rs.InsertedID.(primitive.ObjectID); ok {
-		oidHex = oid.Hex()
+	if len(rs.InsertedIDs) > 0 {
+		if oid, ok := rs.InsertedIDs[0].(primitive.ObjectID); ok {
+			oidHex = oid.Hex()
+		}
 	}
 
 	return oidHex, nil
@@ -0,0 +1,148 @@
+package mongohelper
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// OpKind identifies the kind of an Operation
+type OpKind string
+
+// Kinds of the operations routed through the interceptor chain
+const (
+	OpFind       OpKind = "Find"
+	OpFindOne    OpKind = "FindOne"
+	OpInsertOne  OpKind = "InsertOne"
+	OpInsertMany OpKind = "InsertMany"
+	OpUpdateOne  OpKind = "UpdateOne"
+	OpUpdateMany OpKind = "UpdateMany"
+	OpReplaceOne OpKind = "ReplaceOne"
+	OpDeleteOne  OpKind = "DeleteOne"
+	OpDeleteMany OpKind = "DeleteMany"
+	OpCountDocs  OpKind = "CountDocs"
+	OpAggregate  OpKind = "Aggregate"
+	OpIterate    OpKind = "Iterate"
+	OpBulkWrite  OpKind = "BulkWrite"
+	OpWatch      OpKind = "Watch"
+	// OpListIndexes, OpCreateIndexes and OpDropIndexes are run by EnsureIndexes
+	OpListIndexes   OpKind = "ListIndexes"
+	OpCreateIndexes OpKind = "CreateIndexes"
+	OpDropIndexes   OpKind = "DropIndexes"
+)
+
+// idempotent reports if repeating an operation of kind k can't change the outcome, so it may be retried after any
+// transient failure. Other kinds, mostly writes, are only retried when the command surely didn't reach the server,
+// as the driver itself already retries single document writes safely
+func (k OpKind) idempotent() bool {
+	switch k {
+	case OpFind, OpFindOne, OpCountDocs, OpAggregate, OpIterate, OpWatch, OpListIndexes, OpCreateIndexes, OpDropIndexes:
+		return true
+	}
+
+	return false
+}
+
+// Operation describes a Link call on its way to database
+// Interceptors may change its fields; the database receives what's left after every interceptor ran
+type Operation struct {
+	Kind       OpKind
+	Database   string
+	Collection string
+	// Filter selects the affected documents, if the operation has one
+	Filter interface{}
+	// Update is the update document, or the replacement document for OpReplaceOne
+	Update interface{}
+	// Pipeline is the aggregation pipeline of OpAggregate, or the events pipeline of OpWatch
+	Pipeline interface{}
+	// Documents are the documents to insert
+	Documents []interface{}
+	// Models are the writes of OpBulkWrite, and Ordered its mode
+	Models  []WriteModel
+	Ordered bool
+	// Indexes are the indexes created by OpCreateIndexes, or dropped by OpDropIndexes, that only uses their names
+	Indexes []IndexSpec
+	// Options tunes OpFind, OpFindOne, OpCountDocs and OpIterate. It's never nil for them
+	Options *FindOptions
+	// Dest receives the documents read by OpFind, OpFindOne and OpAggregate
+	Dest interface{}
+}
+
+// OpResult is what an operation produced. Only the fields that make sense for the operation kind are set
+type OpResult struct {
+	InsertedIDs   []interface{}
+	MatchedCount  int64
+	ModifiedCount int64
+	DeletedCount  int64
+	Count         int64
+	// Bulk is the result of OpBulkWrite
+	Bulk *BulkResult
+}
+
+// OpFunc executes an Operation
+type OpFunc func(ctx context.Context, op *Operation) (*OpResult, error)
+
+// Interceptor wraps an OpFunc. It may observe or change the operation, call next, or return without calling it
+type Interceptor func(next OpFunc) OpFunc
+
+// Use appends interceptors to the chain every operation goes through
+// The first interceptor ever added is the outermost one: it sees the operation first and the result last
+func (l *Link) Use(interceptors ...Interceptor) {
+	l.interceptorsMu.Lock()
+
+	defer l.interceptorsMu.Unlock()
+
+	l.interceptors = append(l.interceptors[:len(l.interceptors):len(l.interceptors)], interceptors...)
+}
+
+// run sends op through the interceptor chain, down to fn, that talks to database through execute()
+// All operations must be routed through here
+func (l *Link) run(ctx context.Context, op *Operation, fn func(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error)) (*OpResult, error) {
+	var next OpFunc = func(ctx context.Context, op *Operation) (*OpResult, error) {
+		var rs *OpResult
+
+		start := time.Now()
+
+		var attrs []Field
+
+		// describing op costs an encoding of its filter, update and pipeline, useless when nothing is traced
+		if l.tracing() {
+			attrs = op.attributes()
+		}
+
+		ctx, span := l.startSpan(ctx, string(op.Kind)+" "+op.Database+"."+op.Collection, attrs...)
+
+		err := l.execute(ctx, "link."+string(op.Kind), op.Database, op.Collection, op.Kind.idempotent(), func(ctx context.Context, client *mongo.Client) error {
+			var err error
+
+			rs, err = fn(ctx, client, op)
+
+			return err
+		})
+
+		endSpan(span, err)
+
+		l.metrics().ObserveOperation(op.Kind, op.Database, op.Collection, outcome(err), time.Since(start))
+
+		return rs, err
+	}
+
+	l.interceptorsMu.RLock()
+
+	chain := l.interceptors
+
+	l.interceptorsMu.RUnlock()
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		next = chain[i](next)
+	}
+
+	rs, err := next(ctx, op)
+
+	if err == nil && rs == nil {
+		rs = &OpResult{}
+	}
+
+	return rs, err
+}
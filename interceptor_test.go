@@ -0,0 +1,62 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestLink_Use(t *testing.T) {
+	var (
+		l     Link
+		trail []string
+	)
+
+	// observes every operation, outermost
+	l.Use(func(next OpFunc) OpFunc {
+		return func(ctx context.Context, op *Operation) (*OpResult, error) {
+			trail = append(trail, "before "+string(op.Kind))
+
+			rs, err := next(ctx, op)
+
+			trail = append(trail, "after "+string(op.Kind))
+
+			return rs, err
+		}
+	})
+
+	// adds a tenant condition to every filter, and answers counts without database
+	l.Use(func(next OpFunc) OpFunc {
+		return func(ctx context.Context, op *Operation) (*OpResult, error) {
+			op.Filter = bson.D{{Key: "$and", Value: bson.A{op.Filter, bson.M{"tenant": "t1"}}}}
+
+			if op.Kind == OpCountDocs {
+				return &OpResult{Count: 42}, nil
+			}
+
+			return next(ctx, op)
+		}
+	})
+
+	if n, err := l.CountDocs(testDB, testCollection, bson.M{}); err != nil || n != 42 {
+		t.Errorf("expected the short-circuited count, got %d ( %v )", n, err)
+	}
+
+	if _, err := l.DeleteMany(testDB, testCollection, bson.M{}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("expected the operation to reach the uninitialized link, got %v", err)
+	}
+
+	want := []string{"before CountDocs", "after CountDocs", "before DeleteMany", "after DeleteMany"}
+
+	if len(trail) != len(want) {
+		t.Fatalf("expected %v, got %v", want, trail)
+	}
+
+	for i := range want {
+		if trail[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, trail)
+		}
+	}
+}
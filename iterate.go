@@ -0,0 +1,172 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Iterate streams the documents matching filter to fn, one at a time, so large results are processed at bounded
+// memory. The BatchSize option sets how many documents are held at once
+// fn may return ErrStopIteration to stop early, or any other error to abort, which Iterate returns within an *OpError.
+// raw is only valid during the call; copy it to keep it. The cursor is always closed
+func (l *Link) Iterate(database, collection string, filter interface{}, fn func(raw bson.Raw) error, opts ...FindOption) error {
+	return l.IterateCtx(context.Background(), database, collection, filter, fn, opts...)
+}
+
+// IterateCtx works like Iterate, but honors the cancellation and deadline of the given context
+// The configured execution timeout and retries apply to opening the cursor. The iteration itself only honors ctx,
+// as it may take much longer, and isn't retried, as fn already received documents
+func (l *Link) IterateCtx(ctx context.Context, database, collection string, filter interface{}, fn func(raw bson.Raw) error, opts ...FindOption) error {
+	rs, err := l.openCursor(ctx, database, collection, filter, opts)
+
+	if err != nil {
+		return err
+	}
+
+	if rs == nil {
+		return nil
+	}
+
+	defer l.release(rs.Close)
+
+	for rs.Next(ctx) {
+		if err := fn(rs.Current); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+
+			return opError("link.Iterate", database, collection, 1, err)
+		}
+	}
+
+	if err := rs.Err(); err != nil {
+		return opError("link.Iterate", database, collection, 1, err)
+	}
+
+	return nil
+}
+
+// openCursor runs the query through the interceptor chain, returning the cursor over its results
+// The cursor is nil when an interceptor answered the operation by itself
+func (l *Link) openCursor(ctx context.Context, database, collection string, filter interface{}, opts []FindOption) (*mongo.Cursor, error) {
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	op := Operation{Kind: OpIterate, Database: database, Collection: collection, Filter: filter, Options: findOptions(opts)}
+
+	var rs *mongo.Cursor
+
+	_, err := l.run(ctx, &op, func(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error) {
+		var err error
+
+		rs, err = op.cursor(ctx, client)
+
+		return nil, err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rs, nil
+}
+
+// Cursor streams the documents of a query, decoding them as T
+// It must be closed after use, usually with a deferred call to Close
+type Cursor[T any] struct {
+	link     *Link
+	rs       *mongo.Cursor
+	database string
+	coll     string
+	err      error
+}
+
+// CursorNew runs the query on link, returning a Cursor over its results. It takes the same options as Find,
+// and BatchSize sets how many documents are held at once
+// The configured execution timeout and retries apply to opening the cursor, not to iterating it
+func CursorNew[T any](ctx context.Context, link *Link, database, collection string, filter interface{}, opts ...FindOption) (*Cursor[T], error) {
+	rs, err := link.openCursor(ctx, database, collection, filter, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cursor[T]{link: link, rs: rs, database: database, coll: collection}, nil
+}
+
+// Next advances to the following document, returning false when there are no more documents or on failure
+// Check Err afterwards
+func (c *Cursor[T]) Next(ctx context.Context) bool {
+	if c.rs == nil || c.err != nil {
+		return false
+	}
+
+	return c.rs.Next(ctx)
+}
+
+// Decode decodes the current document
+func (c *Cursor[T]) Decode() (T, error) {
+	var x T
+
+	if c.rs == nil {
+		return x, opError("link.Iterate", c.database, c.coll, 1, mongo.ErrNoDocuments)
+	}
+
+	if err := c.rs.Decode(&x); err != nil {
+		return x, opError("link.Iterate", c.database, c.coll, 1, err)
+	}
+
+	return x, nil
+}
+
+// Raw returns the current document, that's only valid until the following call to Next
+func (c *Cursor[T]) Raw() bson.Raw {
+	if c.rs == nil {
+		return nil
+	}
+
+	return c.rs.Current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (c *Cursor[T]) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+
+	if c.rs == nil {
+		return nil
+	}
+
+	if err := c.rs.Err(); err != nil {
+		return opError("link.Iterate", c.database, c.coll, 1, err)
+	}
+
+	return nil
+}
+
+// Close releases the cursor on server side. It's safe to call more than once
+func (c *Cursor[T]) Close() error {
+	if c.rs == nil {
+		return nil
+	}
+
+	rs := c.rs
+
+	c.rs = nil
+
+	// keeps the iteration error for Err, as the cursor won't be there anymore
+	if err := rs.Err(); err != nil {
+		c.err = opError("link.Iterate", c.database, c.coll, 1, err)
+	}
+
+	if err := c.link.release(rs.Close); err != nil {
+		return opError("link.Iterate", c.database, c.coll, 1, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestLink_Iterate(t *testing.T) {
+	var (
+		l    Link
+		seen *Operation
+	)
+
+	if err := l.Iterate(testDB, testCollection, nil, func(bson.Raw) error { return nil }); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("expected the operation to reach the uninitialized link, got %v", err)
+	}
+
+	// answers every operation without database, as a cache would
+	l.Use(func(next OpFunc) OpFunc {
+		return func(ctx context.Context, op *Operation) (*OpResult, error) {
+			seen = op
+
+			return &OpResult{}, nil
+		}
+	})
+
+	called := false
+
+	if err := l.Iterate(testDB, testCollection, nil, func(bson.Raw) error { called = true; return nil }, BatchSize(100)); err != nil || called {
+		t.Errorf("expected an empty iteration, got %v", err)
+	}
+
+	if seen == nil || seen.Kind != OpIterate || seen.Options.BatchSize != 100 {
+		t.Errorf("expected the batch size to reach the interceptors, got %+v", seen)
+	}
+
+	c, err := CursorNew[testDocStruct](context.Background(), &l, testDB, testCollection, bson.M{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Next(context.Background()) || c.Err() != nil || c.Raw() != nil {
+		t.Error("expected an empty cursor")
+	}
+
+	if _, err := c.Decode(); err == nil {
+		t.Error("expected an error decoding from an empty cursor")
+	}
+
+	if c.Close() != nil || c.Close() != nil {
+		t.Error("expected Close to be idempotent")
+	}
+}
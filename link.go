@@ -1,88 +1,157 @@
 package mongohelper
 
 import (
-	"log"
+	"context"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // Link is a concentrator wrapper for mongodb client
+// It's safe for concurrent use
 type Link struct {
+	// mu guards client, that's replaced on reconnections
+	mu      sync.RWMutex
 	client  *mongo.Client
 	options Options
+	// flightMu guards flight
+	flightMu sync.Mutex
+	// flight is the reconnection in progress, if any
+	flight *reconnectFlight
+	// state holds the current State, accessed atomically
+	state int32
+	// supervisor is the background health check routine, or nil if not enabled
+	supervisor *supervisor
+	// events keeps the handlers registered with Subscribe()
+	events events
+	// tracker enforces the (re)connection attempts and time limits
+	tracker *reconnectTracker
+	// interceptorsMu guards interceptors
+	interceptorsMu sync.RWMutex
+	// interceptors are the chain added with Use()
+	interceptors []Interceptor
+	// watchersMu guards watchers
+	watchersMu sync.Mutex
+	// watchers are the running change stream subscriptions, stopped by Disconnect()
+	watchers map[*Watcher]struct{}
+}
+
+// linkNew returns a disconnected Link using the given options
+func linkNew(opts Options) *Link {
+	return &Link{
+		options: opts,
+		tracker: newReconnectTracker(opts.reconnectionAttemptsLimit, opts.reconnectionTimeLimit, nil),
+	}
+}
+
+// currentClient returns the client in use
+func (l *Link) currentClient() *mongo.Client {
+	l.mu.RLock()
+
+	defer l.mu.RUnlock()
+
+	return l.client
 }
 
 // insistOnFail returns l.options.reconnectionInsistOnFail value
-func (l Link) insistOnFail() bool {
+func (l *Link) insistOnFail() bool {
 	return l.options.reconnectionInsistOnFail
 }
 
 // canInsist checks if this engine can retry to connect database, considering the options rules
-func (l Link) canInsist() bool {
-	if l.options.reconnectionAttemptsLimit > 0 && l.options.attempts < l.options.reconnectionAttemptsLimit {
-		return true
-	}
-
-	if l.options.reconnectionAttemptsLimitMinutes > 0 {
-		expiration := l.options.lastConnection.Add(time.Duration(l.options.reconnectionAttemptsLimitMinutes) * time.Minute)
+func (l *Link) canInsist() bool {
+	return l.tracker.canRetry()
+}
 
-		if time.Now().After(expiration) {
-			return true
-		}
+// retryPolicy returns the configured RetryPolicy, or a ConstantBackoff using the reconnection interval
+func (l *Link) retryPolicy() RetryPolicy {
+	if l.options.retryPolicy != nil {
+		return l.options.retryPolicy
 	}
 
-	return false
+	return ConstantBackoff{Interval: l.options.reconnectionInterval}
 }
 
-// wait N seconds before next (9)re)connection attempt
-func (l Link) wait() {
-	timeout := time.Duration(l.options.reconnectionSecondsBetweenAttempts) * time.Second
-
-	time.Sleep(timeout)
+// wait the retry policy backoff before the given (re)connection attempt, or less if ctx is done first
+func (l *Link) wait(ctx context.Context, attempt uint) error {
+	return sleep(ctx, l.retryPolicy().Backoff(attempt))
 }
 
-// increment increments in one the connection attempt counter
-func (l *Link) increment() {
-	if l.options.reconnectionAttemptsLimit > 0 {
-		l.options.attempts++
+// sleep waits d, or less if ctx is done first, returning ctx error in that case
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-// notifyConnection set attempts to zero and lastConnection to NOW
+// notifyConnection resets the reconnection budget and marks the link as connected
 func (l *Link) notifyConnection() {
-	if l.options.reconnectionAttemptsLimit > 0 {
-		l.options.attempts = 0
-	}
+	l.tracker.success()
+
+	l.setState(StateConnected)
 
-	if l.options.reconnectionAttemptsLimitMinutes > 0 {
-		l.options.lastConnection = time.Now()
+	l.logger().Info("mongodb connected", Field{FieldRoutine, "link.notifyConnection"})
+
+	l.emit(EventConnected, "link.notifyConnection", nil)
+}
+
+// metrics returns the Metrics given in options, or a NopMetrics
+func (l *Link) metrics() Metrics {
+	if l.options.metrics == nil {
+		return NopMetrics{}
 	}
 
-	if l.options.printLogMessages {
-		l.log("link.notifyConnection", "mongodb connected")
+	return l.options.metrics
+}
+
+// tracer returns the Tracer given in options, or a NopTracer
+func (l *Link) tracer() Tracer {
+	if l.options.tracer == nil {
+		return NopTracer{}
 	}
+
+	return l.options.tracer
 }
 
-// log print log message if allowed by programmer in options
-func (l Link) log(routine, message string) {
-	if l.options.printLogMessages {
-		log.Printf("%s - mongohelper %s - %s\n", time.Now().Format(time.RFC3339), routine, message)
+// logger returns the Logger given in options, or a NopLogger
+func (l *Link) logger() Logger {
+	if l.options.logger == nil {
+		return NopLogger{}
 	}
+
+	return l.options.logger
 }
 
-func (l Link) appName() string {
+func (l *Link) appName() string {
 	return l.options.appName
 }
 
-func (l Link) connectionString() string {
+func (l *Link) connectionString() string {
 	return l.options.connString
 }
 
-func (l Link) connTimeout() time.Duration {
-	return time.Duration(l.options.connTimeoutSeconds) * time.Second
+func (l *Link) connTimeout() time.Duration {
+	return l.options.connTimeout
+}
+
+// release runs fn, that frees some resource, with a context bounded by the connection timeout
+// It's detached from the operation context, as that one may be already done
+func (l *Link) release(fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.connTimeout())
+
+	defer cancel()
+
+	return fn(ctx)
 }
 
-func (l Link) execTimeout() time.Duration {
-	return time.Duration(l.options.execTimeoutSeconds) * time.Second
+func (l *Link) execTimeout() time.Duration {
+	return l.options.execTimeout
 }
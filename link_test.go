@@ -0,0 +1,140 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestLink_concurrentReconnect(t *testing.T) {
+	l := unreachableLink(t)
+
+	var reconnections int32
+
+	l.Subscribe(func(e Event) {
+		if e.Type == EventPingFailed && e.Routine == "link.connect" {
+			atomic.AddInt32(&reconnections, 1)
+		}
+	})
+
+	// simulates a client closed underneath the link, as if Disconnect() was called on it
+	if err := l.currentClient().Disconnect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := l.CountDocs(testDB, testCollection, bson.M{}); err == nil {
+				t.Error("expected an error from an unreachable server")
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&reconnections); n != 1 {
+		t.Errorf("expected a single reconnection, got %d", n)
+	}
+
+	l.Disconnect()
+}
+
+func TestLink_replacedClientIsClosed(t *testing.T) {
+	l := unreachableLink(t)
+
+	old := l.currentClient()
+
+	if err := l.reconnect(context.Background(), false, nil); err == nil {
+		t.Fatal("expected reconnection to fail")
+	}
+
+	if l.currentClient() == old {
+		t.Fatal("expected the client to be replaced")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		_, err := old.ListDatabaseNames(context.Background(), bson.M{})
+
+		if errors.Is(err, mongo.ErrClientDisconnected) {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the replaced client to be disconnected, got %v", err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	l.Disconnect()
+}
+
+func TestLink_contextCancellation(t *testing.T) {
+	// the server selection would take 5s without the context deadline
+	l := linkNew(*NewOptions("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=5000"))
+	l.options.connTimeout = 50 * time.Millisecond
+	l.options.reconnectionInterval = time.Hour
+
+	if err := l.connectCtx(context.Background(), false); err == nil {
+		t.Fatal("expected connection to fail")
+	}
+
+	defer l.Disconnect()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+
+	cancel()
+
+	var a []bson.M
+
+	start := time.Now()
+
+	if err := l.FindCtx(cancelled, testDB, testCollection, bson.M{}, &a); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	expiring, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+
+	defer cancel()
+
+	if err := l.FindCtx(expiring, testDB, testCollection, bson.M{}, &a); !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("expected FindCtx to give up with its context, took %s", d)
+	}
+
+	// the reconnection waits an hour between attempts, unless its context is done
+	expiring, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+
+	defer cancel()
+
+	start = time.Now()
+
+	if err := l.wait(expiring, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	if err := l.connectCtx(expiring, true); err == nil {
+		t.Error("expected the insisting connection to give up with its context")
+	}
+
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("expected the reconnection to give up with its context, took %s", d)
+	}
+}
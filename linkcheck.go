@@ -1,12 +1,17 @@
 package mongohelper
 
-import "fmt"
+// linkCheck returns an *OpError matching ErrNotConnected if the link was not initialized or was closed
+func (l *Link) linkCheck(routine, database, collection string) error {
+	if l.currentClient() == nil {
+		l.logger().Error("use of uninitialized connection", Field{FieldRoutine, routine})
 
-func (l Link) linkCheck(routine string) error {
-	if l.client == nil {
-		l.log(routine, "use of uninitialized connection")
+		return &OpError{Op: opName(routine), Database: database, Collection: collection, Kind: ErrNotConnected, Err: errUninitialized}
+	}
+
+	if l.State() == StateClosed {
+		l.logger().Error("use of closed connection", Field{FieldRoutine, routine})
 
-		return fmt.Errorf("use of uninitialized connection")
+		return &OpError{Op: opName(routine), Database: database, Collection: collection, Kind: ErrNotConnected, Err: errLinkClosed}
 	}
 
 	return nil
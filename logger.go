@@ -0,0 +1,130 @@
+package mongohelper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Logger receives the engine log messages, with key-value fields like routine, database, collection,
+// duration and attempt
+type Logger interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+}
+
+// Field is a key-value pair attached to a log message or to a span
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Keys of the fields the engine attaches to its log messages
+const (
+	FieldRoutine    = "routine"
+	FieldDatabase   = "database"
+	FieldCollection = "collection"
+	FieldDuration   = "duration"
+	FieldAttempt    = "attempt"
+	FieldError      = "error"
+)
+
+// NopLogger discards every message
+type NopLogger struct{}
+
+// Debug discards the message
+func (NopLogger) Debug(string, ...Field) {}
+
+// Info discards the message
+func (NopLogger) Info(string, ...Field) {}
+
+// Warn discards the message
+func (NopLogger) Warn(string, ...Field) {}
+
+// Error discards the message
+func (NopLogger) Error(string, ...Field) {}
+
+// slogLogger adapts a *slog.Logger
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// SlogLogger returns a Logger that writes to l, turning fields into slog attributes
+// If l is nil, slog.Default() is used
+func SlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+
+	return slogLogger{l: l}
+}
+
+func (s slogLogger) log(level slog.Level, msg string, fields []Field) {
+	ctx := context.Background()
+
+	if !s.l.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, len(fields))
+
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+
+	s.l.LogAttrs(ctx, level, msg, attrs...)
+}
+
+func (s slogLogger) Debug(msg string, fields ...Field) { s.log(slog.LevelDebug, msg, fields) }
+func (s slogLogger) Info(msg string, fields ...Field)  { s.log(slog.LevelInfo, msg, fields) }
+func (s slogLogger) Warn(msg string, fields ...Field)  { s.log(slog.LevelWarn, msg, fields) }
+func (s slogLogger) Error(msg string, fields ...Field) { s.log(slog.LevelError, msg, fields) }
+
+// stdLogger adapts a *log.Logger, keeping the engine's classic message format
+type stdLogger struct {
+	l     *log.Logger
+	debug bool
+}
+
+// StdLogger returns a Logger that prints to l, in plain text. Debug messages are printed only if debug is true
+// If l is nil, log.Default() is used
+func StdLogger(l *log.Logger, debug bool) Logger {
+	if l == nil {
+		l = log.Default()
+	}
+
+	return stdLogger{l: l, debug: debug}
+}
+
+func (s stdLogger) log(level, msg string, fields []Field) {
+	routine := ""
+
+	var sb strings.Builder
+
+	for _, f := range fields {
+		if f.Key == FieldRoutine {
+			routine = fmt.Sprint(f.Value)
+
+			continue
+		}
+
+		fmt.Fprintf(&sb, " %s=%v", f.Key, f.Value)
+	}
+
+	s.l.Printf("%s - mongohelper %s %s - %s%s\n", time.Now().Format(time.RFC3339), level, routine, msg, sb.String())
+}
+
+func (s stdLogger) Debug(msg string, fields ...Field) {
+	if s.debug {
+		s.log("DEBUG", msg, fields)
+	}
+}
+
+func (s stdLogger) Info(msg string, fields ...Field)  { s.log("INFO", msg, fields) }
+func (s stdLogger) Warn(msg string, fields ...Field)  { s.log("WARN", msg, fields) }
+func (s stdLogger) Error(msg string, fields ...Field) { s.log("ERROR", msg, fields) }
@@ -0,0 +1,44 @@
+package mongohelper
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := SlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	l.Debug("hidden", Field{FieldRoutine, "link.Find"})
+	l.Warn("operation failed, retrying", Field{FieldRoutine, "link.Find"}, Field{FieldDatabase, testDB}, Field{FieldAttempt, uint(2)})
+
+	var m map[string]interface{}
+
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("expected exactly one JSON record, got %q: %v", buf.String(), err)
+	}
+
+	if m["level"] != "WARN" || m[FieldRoutine] != "link.Find" || m[FieldDatabase] != testDB || m[FieldAttempt] != float64(2) {
+		t.Errorf("unexpected record %v", m)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := StdLogger(log.New(&buf, "", 0), false)
+
+	l.Debug("hidden")
+	l.Error("use of closed connection", Field{FieldRoutine, "link.Find"}, Field{FieldCollection, testCollection})
+
+	s := buf.String()
+
+	if strings.Contains(s, "hidden") || !strings.Contains(s, "ERROR link.Find - use of closed connection collection="+testCollection) {
+		t.Errorf("unexpected output %q", s)
+	}
+}
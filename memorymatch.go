@@ -0,0 +1,890 @@
+package mongohelper
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// toDoc converts any document accepted by the driver (structs, maps, bson.D, ...) into a bson.D
+// Nested documents become bson.D and arrays become bson.A, as the driver decodes them
+func toDoc(v interface{}) (bson.D, error) {
+	if v == nil {
+		return bson.D{}, nil
+	}
+
+	b, err := bson.Marshal(v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var d bson.D
+
+	if err := bson.Unmarshal(b, &d); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// docGet returns the value of key in d
+func docGet(d bson.D, key string) (interface{}, bool) {
+	for _, e := range d {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// lookup returns every value reachable through the dotted path, fanning out on arrays of documents
+func lookup(v interface{}, path []string) []interface{} {
+	if len(path) == 0 {
+		return []interface{}{v}
+	}
+
+	switch x := v.(type) {
+	case bson.D:
+		child, ok := docGet(x, path[0])
+
+		if !ok {
+			return nil
+		}
+
+		return lookup(child, path[1:])
+	case bson.A:
+		if i, err := strconv.Atoi(path[0]); err == nil {
+			if i < 0 || i >= len(x) {
+				return nil
+			}
+
+			return lookup(x[i], path[1:])
+		}
+
+		var a []interface{}
+
+		for _, item := range x {
+			if _, ok := item.(bson.D); ok {
+				a = append(a, lookup(item, path)...)
+			}
+		}
+
+		return a
+	}
+
+	return nil
+}
+
+// matches reports if doc satisfies the filter
+func matches(doc bson.D, filter bson.D) (bool, error) {
+	for _, e := range filter {
+		ok, err := matchElement(doc, e)
+
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// matchElement evaluates one filter element: a logical operator or a field condition
+func matchElement(doc bson.D, e bson.E) (bool, error) {
+	switch e.Key {
+	case "$and", "$or", "$nor":
+		clauses, ok := e.Value.(bson.A)
+
+		if !ok || len(clauses) == 0 {
+			return false, fmt.Errorf("%s must be a nonempty array", e.Key)
+		}
+
+		for _, c := range clauses {
+			sub, ok := c.(bson.D)
+
+			if !ok {
+				return false, fmt.Errorf("%s entries must be documents", e.Key)
+			}
+
+			m, err := matches(doc, sub)
+
+			if err != nil {
+				return false, err
+			}
+
+			switch {
+			case e.Key == "$and" && !m:
+				return false, nil
+			case e.Key == "$or" && m:
+				return true, nil
+			case e.Key == "$nor" && m:
+				return false, nil
+			}
+		}
+
+		return e.Key != "$or", nil
+	}
+
+	if strings.HasPrefix(e.Key, "$") {
+		return false, fmt.Errorf("unsupported top level operator %s", e.Key)
+	}
+
+	values := lookup(doc, strings.Split(e.Key, "."))
+
+	if cond, ok := e.Value.(bson.D); ok && len(cond) > 0 && strings.HasPrefix(cond[0].Key, "$") {
+		for _, op := range cond {
+			m, err := matchOperator(values, op)
+
+			if err != nil || !m {
+				return false, err
+			}
+		}
+
+		return true, nil
+	}
+
+	return anyEqual(values, e.Value), nil
+}
+
+// matchOperator evaluates a field operator against the values found for the field
+func matchOperator(values []interface{}, op bson.E) (bool, error) {
+	switch op.Key {
+	case "$eq":
+		return anyEqual(values, op.Value), nil
+	case "$ne":
+		return !anyEqual(values, op.Value), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		for _, v := range candidates(values) {
+			c, ok := compare(v, op.Value)
+
+			if !ok {
+				continue
+			}
+
+			if (op.Key == "$gt" && c > 0) || (op.Key == "$gte" && c >= 0) || (op.Key == "$lt" && c < 0) || (op.Key == "$lte" && c <= 0) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case "$in", "$nin":
+		list, ok := op.Value.(bson.A)
+
+		if !ok {
+			return false, fmt.Errorf("%s needs an array", op.Key)
+		}
+
+		found := false
+
+		for _, item := range list {
+			if anyEqual(values, item) {
+				found = true
+
+				break
+			}
+		}
+
+		return found == (op.Key == "$in"), nil
+	case "$exists":
+		return (len(values) > 0) == truthy(op.Value), nil
+	case "$not":
+		switch x := op.Value.(type) {
+		case bson.D:
+			for _, sub := range x {
+				m, err := matchOperator(values, sub)
+
+				if err != nil || !m {
+					return err == nil, err
+				}
+			}
+
+			return false, nil
+		case primitive.Regex:
+			m, err := matchOperator(values, bson.E{Key: "$regex", Value: x})
+
+			return !m, err
+		}
+
+		return false, fmt.Errorf("$not needs a document or a regular expression")
+	case "$regex":
+		re, err := toRegexp(op.Value)
+
+		if err != nil {
+			return false, err
+		}
+
+		for _, v := range candidates(values) {
+			if s, ok := v.(string); ok && re.MatchString(s) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case "$size":
+		n, ok := toFloat(op.Value)
+
+		if !ok {
+			return false, fmt.Errorf("$size needs a number")
+		}
+
+		for _, v := range values {
+			if arr, ok := v.(bson.A); ok && float64(len(arr)) == n {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case "$all":
+		list, ok := op.Value.(bson.A)
+
+		if !ok {
+			return false, fmt.Errorf("$all needs an array")
+		}
+
+		for _, item := range list {
+			if !anyEqual(values, item) {
+				return false, nil
+			}
+		}
+
+		return len(list) > 0, nil
+	case "$elemMatch":
+		cond, ok := op.Value.(bson.D)
+
+		if !ok {
+			return false, fmt.Errorf("$elemMatch needs a document")
+		}
+
+		for _, v := range values {
+			arr, _ := v.(bson.A)
+
+			for _, item := range arr {
+				m, err := matchItem(item, cond)
+
+				if err != nil || m {
+					return m, err
+				}
+			}
+		}
+
+		return false, nil
+	}
+
+	return false, fmt.Errorf("unsupported operator %s", op.Key)
+}
+
+// matchItem evaluates an $elemMatch condition against one array element
+// The condition either has operators applied to the element itself, or a filter applied to the element as a document
+func matchItem(item interface{}, cond bson.D) (bool, error) {
+	if len(cond) > 0 && strings.HasPrefix(cond[0].Key, "$") && cond[0].Key != "$and" && cond[0].Key != "$or" && cond[0].Key != "$nor" {
+		for _, op := range cond {
+			m, err := matchOperator([]interface{}{item}, op)
+
+			if err != nil || !m {
+				return false, err
+			}
+		}
+
+		return true, nil
+	}
+
+	doc, ok := item.(bson.D)
+
+	if !ok {
+		return false, nil
+	}
+
+	return matches(doc, cond)
+}
+
+// toRegexp compiles a $regex argument, given as a regular expression or as a string
+func toRegexp(v interface{}) (*regexp.Regexp, error) {
+	var pattern, options string
+
+	switch x := v.(type) {
+	case primitive.Regex:
+		pattern, options = x.Pattern, x.Options
+	case string:
+		pattern = x
+	default:
+		return nil, fmt.Errorf("$regex needs a regular expression or a string")
+	}
+
+	var flags string
+
+	for _, o := range options {
+		if strings.ContainsRune("ims", o) {
+			flags += string(o)
+		}
+	}
+
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+
+	return regexp.Compile(pattern)
+}
+
+// candidates returns the values and, for arrays, their elements, as the server does when comparing
+func candidates(values []interface{}) []interface{} {
+	var a []interface{}
+
+	for _, v := range values {
+		a = append(a, v)
+
+		if arr, ok := v.(bson.A); ok {
+			a = append(a, arr...)
+		}
+	}
+
+	return a
+}
+
+// anyEqual reports if any value, or any element of an array value, equals target
+// A nil target also matches missing fields
+func anyEqual(values []interface{}, target interface{}) bool {
+	if len(values) == 0 {
+		return target == nil
+	}
+
+	for _, v := range candidates(values) {
+		if equal(v, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contains reports if any element of arr equals v
+func contains(arr bson.A, v interface{}) bool {
+	for _, item := range arr {
+		if equal(item, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// equal compares two bson values, numbers by their numeric value
+func equal(a, b interface{}) bool {
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// compare orders two bson values of compatible types, returning ok false when they can't be compared
+func compare(a, b interface{}) (int, bool) {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+
+			return 0, true
+		}
+
+		return 0, false
+	}
+
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, true
+			case !x:
+				return -1, true
+			}
+
+			return 1, true
+		}
+	case primitive.ObjectID:
+		if y, ok := b.(primitive.ObjectID); ok {
+			return bytes.Compare(x[:], y[:]), true
+		}
+	case primitive.DateTime:
+		if y, ok := toDateTime(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+
+			return 0, true
+		}
+	}
+
+	return 0, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+
+	return 0, false
+}
+
+func toDateTime(v interface{}) (primitive.DateTime, bool) {
+	switch x := v.(type) {
+	case primitive.DateTime:
+		return x, true
+	case time.Time:
+		return primitive.NewDateTimeFromTime(x), true
+	}
+
+	return 0, false
+}
+
+// truthy interprets operator arguments like $exists: 1 or $exists: true
+func truthy(v interface{}) bool {
+	if b, ok := v.(bool); ok {
+		return b
+	}
+
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+
+	return v != nil
+}
+
+// sortLess reports if doc a comes before doc b in the given sort order
+// Missing fields come first, as the server does with nulls; values that can't be compared are kept in place
+func sortLess(a, b bson.D, order bson.D) bool {
+	for _, e := range order {
+		path := strings.Split(e.Key, ".")
+
+		va, vb := lookup(a, path), lookup(b, path)
+
+		var c int
+
+		switch {
+		case len(va) == 0 && len(vb) == 0:
+			continue
+		case len(va) == 0:
+			c = -1
+		case len(vb) == 0:
+			c = 1
+		default:
+			c, _ = compare(va[0], vb[0])
+		}
+
+		if c == 0 {
+			continue
+		}
+
+		if f, _ := toFloat(e.Value); f < 0 {
+			return c > 0
+		}
+
+		return c < 0
+	}
+
+	return false
+}
+
+// project returns a copy of doc with the fields selected by projection
+// Projections either include fields, always keeping _id unless it's excluded, or exclude fields
+func project(doc bson.D, projection bson.D) (bson.D, error) {
+	include := false
+
+	for _, e := range projection {
+		if e.Key != "_id" && truthy(e.Value) {
+			include = true
+		}
+	}
+
+	if !include {
+		out := cloneDoc(doc)
+
+		for _, e := range projection {
+			out = unsetPath(out, strings.Split(e.Key, "."))
+		}
+
+		return out, nil
+	}
+
+	out := bson.D{}
+
+	if v, ok := docGet(projection, "_id"); !ok || truthy(v) {
+		if id, ok := docGet(doc, "_id"); ok {
+			out = append(out, bson.E{Key: "_id", Value: cloneValue(id)})
+		}
+	}
+
+	for _, e := range projection {
+		if e.Key == "_id" || !truthy(e.Value) {
+			continue
+		}
+
+		path := strings.Split(e.Key, ".")
+
+		if values := lookup(doc, path); len(values) > 0 {
+			var err error
+
+			if out, err = setPath(out, path, cloneValue(values[0])); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// isUpdateDocument reports if d is made of update operators, instead of being a replacement
+func isUpdateDocument(d bson.D) bool {
+	return len(d) > 0 && strings.HasPrefix(d[0].Key, "$")
+}
+
+// applyUpdate returns a copy of doc modified by the update operators
+func applyUpdate(doc bson.D, update bson.D) (bson.D, error) {
+	doc = cloneDoc(doc)
+
+	for _, op := range update {
+		fields, ok := op.Value.(bson.D)
+
+		if !ok {
+			return nil, fmt.Errorf("%s needs a document", op.Key)
+		}
+
+		for _, f := range fields {
+			path := strings.Split(f.Key, ".")
+
+			if path[0] == "_id" {
+				if old, ok := docGet(doc, "_id"); op.Key != "$set" || len(path) > 1 || !ok || !equal(old, f.Value) {
+					return nil, fmt.Errorf("the _id field cannot be modified")
+				}
+			}
+
+			var err error
+
+			switch op.Key {
+			case "$set":
+				doc, err = setPath(doc, path, f.Value)
+			case "$unset":
+				doc = unsetPath(doc, path)
+			case "$inc":
+				current := lookup(doc, path)
+
+				if len(current) == 0 {
+					doc, err = setPath(doc, path, f.Value)
+
+					break
+				}
+
+				doc, err = setPath(doc, path, addNumbers(current[0], f.Value))
+			case "$push":
+				var items bson.A
+
+				if each, ok := f.Value.(bson.D); ok && len(each) > 0 && each[0].Key == "$each" {
+					items, _ = each[0].Value.(bson.A)
+				} else {
+					items = bson.A{f.Value}
+				}
+
+				var arr bson.A
+
+				if current := lookup(doc, path); len(current) > 0 {
+					if arr, ok = current[0].(bson.A); !ok {
+						return nil, fmt.Errorf("the field %s must be an array", f.Key)
+					}
+				}
+
+				doc, err = setPath(doc, path, append(append(bson.A{}, arr...), items...))
+			case "$mul":
+				current := lookup(doc, path)
+
+				if len(current) == 0 {
+					doc, err = setPath(doc, path, multiplyNumbers(int32(0), f.Value))
+
+					break
+				}
+
+				doc, err = setPath(doc, path, multiplyNumbers(current[0], f.Value))
+			case "$min", "$max":
+				current := lookup(doc, path)
+
+				if len(current) > 0 {
+					c, ok := compare(f.Value, current[0])
+
+					if !ok || (op.Key == "$min" && c >= 0) || (op.Key == "$max" && c <= 0) {
+						break
+					}
+				}
+
+				doc, err = setPath(doc, path, f.Value)
+			case "$addToSet":
+				var items bson.A
+
+				if each, ok := f.Value.(bson.D); ok && len(each) > 0 && each[0].Key == "$each" {
+					items, _ = each[0].Value.(bson.A)
+				} else {
+					items = bson.A{f.Value}
+				}
+
+				var arr bson.A
+
+				if current := lookup(doc, path); len(current) > 0 {
+					if arr, ok = current[0].(bson.A); !ok {
+						return nil, fmt.Errorf("the field %s must be an array", f.Key)
+					}
+				}
+
+				arr = append(bson.A{}, arr...)
+
+				// whole elements are compared: an array element is a value of its own, not a set of candidates
+				for _, item := range items {
+					if !contains(arr, item) {
+						arr = append(arr, item)
+					}
+				}
+
+				doc, err = setPath(doc, path, arr)
+			case "$pull":
+				current := lookup(doc, path)
+
+				if len(current) == 0 {
+					break
+				}
+
+				arr, ok := current[0].(bson.A)
+
+				if !ok {
+					return nil, fmt.Errorf("the field %s must be an array", f.Key)
+				}
+
+				kept := bson.A{}
+
+				for _, item := range arr {
+					var m bool
+
+					if cond, ok := f.Value.(bson.D); ok {
+						m, err = matchItem(item, cond)
+					} else {
+						m = equal(item, f.Value)
+					}
+
+					if err != nil {
+						return nil, err
+					}
+
+					if !m {
+						kept = append(kept, item)
+					}
+				}
+
+				doc, err = setPath(doc, path, kept)
+			case "$rename":
+				to, ok := f.Value.(string)
+
+				if !ok {
+					return nil, fmt.Errorf("$rename needs a string")
+				}
+
+				if current := lookup(doc, path); len(current) > 0 {
+					doc = unsetPath(doc, path)
+					doc, err = setPath(doc, strings.Split(to, "."), current[0])
+				}
+			case "$currentDate":
+				doc, err = setPath(doc, path, primitive.NewDateTimeFromTime(time.Now()))
+			case "$setOnInsert":
+				// MemoryStore doesn't upsert, so there's never an insertion
+			default:
+				return nil, fmt.Errorf("unsupported update operator %s", op.Key)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return doc, nil
+}
+
+// addNumbers sums two numeric bson values, keeping integers when possible
+// Like the server, an int32 sum that overflows becomes an int64
+func addNumbers(a, b interface{}) interface{} {
+	switch x := a.(type) {
+	case int32:
+		if y, ok := b.(int32); ok {
+			return narrowInt(int64(x) + int64(y))
+		}
+	case int64:
+		switch y := b.(type) {
+		case int32:
+			return x + int64(y)
+		case int64:
+			return x + y
+		}
+	}
+
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			if _, isFloat := a.(float64); !isFloat {
+				if _, isFloat := b.(float64); !isFloat {
+					return int64(x + y)
+				}
+			}
+
+			return x + y
+		}
+	}
+
+	return b
+}
+
+// multiplyNumbers multiplies two numeric bson values, keeping integers when possible, and promoting int32 as addNumbers
+func multiplyNumbers(a, b interface{}) interface{} {
+	x, okA := toFloat(a)
+	y, okB := toFloat(b)
+
+	if !okA || !okB {
+		return b
+	}
+
+	_, floatA := a.(float64)
+	_, floatB := b.(float64)
+
+	if floatA || floatB {
+		return x * y
+	}
+
+	if x, ok := a.(int32); ok {
+		if y, ok := b.(int32); ok {
+			return narrowInt(int64(x) * int64(y))
+		}
+	}
+
+	return int64(x * y)
+}
+
+// narrowInt returns n as an int32 if it fits, or as an int64 otherwise
+func narrowInt(n int64) interface{} {
+	if n < math.MinInt32 || n > math.MaxInt32 {
+		return n
+	}
+
+	return int32(n)
+}
+
+// setPath sets value at the dotted path, creating the missing documents
+func setPath(doc bson.D, path []string, value interface{}) (bson.D, error) {
+	for i, e := range doc {
+		if e.Key != path[0] {
+			continue
+		}
+
+		if len(path) == 1 {
+			doc[i].Value = value
+
+			return doc, nil
+		}
+
+		child, ok := e.Value.(bson.D)
+
+		if !ok {
+			return nil, fmt.Errorf("cannot create field %s in %v", path[1], e.Value)
+		}
+
+		child, err := setPath(child, path[1:], value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		doc[i].Value = child
+
+		return doc, nil
+	}
+
+	if len(path) == 1 {
+		return append(doc, bson.E{Key: path[0], Value: value}), nil
+	}
+
+	child, err := setPath(bson.D{}, path[1:], value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(doc, bson.E{Key: path[0], Value: child}), nil
+}
+
+// unsetPath removes the field at the dotted path, if present
+func unsetPath(doc bson.D, path []string) bson.D {
+	for i, e := range doc {
+		if e.Key != path[0] {
+			continue
+		}
+
+		if len(path) == 1 {
+			return append(doc[:i:i], doc[i+1:]...)
+		}
+
+		if child, ok := e.Value.(bson.D); ok {
+			doc[i].Value = unsetPath(child, path[1:])
+		}
+
+		return doc
+	}
+
+	return doc
+}
+
+// cloneDoc returns a deep copy of d, so stored documents never share memory with callers
+func cloneDoc(d bson.D) bson.D {
+	c := make(bson.D, len(d))
+
+	for i, e := range d {
+		c[i] = bson.E{Key: e.Key, Value: cloneValue(e.Value)}
+	}
+
+	return c
+}
+
+func cloneValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case bson.D:
+		return cloneDoc(x)
+	case bson.A:
+		c := make(bson.A, len(x))
+
+		for i, item := range x {
+			c[i] = cloneValue(item)
+		}
+
+		return c
+	}
+
+	return v
+}
@@ -0,0 +1,504 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MemoryStore is an in-memory Store, meant for unit tests that shouldn't depend on a running database
+// It understands the filter operators $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $exists, $and, $or, $nor,
+// $not, $regex, $all, $size and $elemMatch, the update operators $set, $unset, $inc, $mul, $min, $max, $push,
+// $addToSet, $pull, $rename and $currentDate, and the Sort, Skip, Limit and Projection find options
+// Errors are returned as *OpError, like Link does. It's safe for concurrent use
+type MemoryStore struct {
+	mu sync.RWMutex
+	// data holds the documents by database and collection, in insertion order
+	data map[string]map[string][]bson.D
+}
+
+// MemoryStoreNew returns an empty MemoryStore
+func MemoryStoreNew() *MemoryStore {
+	return &MemoryStore{data: make(map[string]map[string][]bson.D)}
+}
+
+// collection returns the documents of the given collection. The caller must hold the lock
+func (m *MemoryStore) collection(database, collection string) []bson.D {
+	return m.data[database][collection]
+}
+
+// setCollection replaces the documents of the given collection. The caller must hold the write lock
+func (m *MemoryStore) setCollection(database, collection string, docs []bson.D) {
+	if m.data[database] == nil {
+		m.data[database] = make(map[string][]bson.D)
+	}
+
+	m.data[database][collection] = docs
+}
+
+// filtered returns the indexes of the documents that match filter. The caller must hold the lock
+func (m *MemoryStore) filtered(database, collection string, filter interface{}, limit int) ([]int, error) {
+	f, err := toDoc(filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []int
+
+	for i, doc := range m.collection(database, collection) {
+		ok, err := matches(doc, f)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			indexes = append(indexes, i)
+
+			if limit > 0 && len(indexes) == limit {
+				break
+			}
+		}
+	}
+
+	return indexes, nil
+}
+
+// query returns the documents matching filter, sorted, skipped, limited and projected as o says
+// Hint, Collation, MaxTime, BatchSize and AllowDiskUse don't change the results, so they're ignored
+// The caller must hold the lock, and must not modify the returned documents
+func (m *MemoryStore) query(database, collection string, filter interface{}, o *FindOptions) ([]bson.D, error) {
+	indexes, err := m.filtered(database, collection, filter, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	all := m.collection(database, collection)
+	docs := make([]bson.D, len(indexes))
+
+	for i, idx := range indexes {
+		docs[i] = all[idx]
+	}
+
+	if len(o.Sort) > 0 {
+		sort.SliceStable(docs, func(i, j int) bool {
+			return sortLess(docs[i], docs[j], o.Sort)
+		})
+	}
+
+	if o.Skip > 0 {
+		if o.Skip >= int64(len(docs)) {
+			return nil, nil
+		}
+
+		docs = docs[o.Skip:]
+	}
+
+	// negative limits mean a single batch, on server side
+	if limit := o.Limit; limit != 0 {
+		if limit < 0 {
+			limit = -limit
+		}
+
+		if limit < int64(len(docs)) {
+			docs = docs[:limit]
+		}
+	}
+
+	if o.Projection != nil {
+		projection, err := toDoc(o.Projection)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for i, doc := range docs {
+			if docs[i], err = project(doc, projection); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return docs, nil
+}
+
+// decode copies doc into dest, the same way the driver does
+func decode(doc bson.D, dest interface{}) error {
+	b, err := bson.Marshal(doc)
+
+	if err != nil {
+		return err
+	}
+
+	return bson.Unmarshal(b, dest)
+}
+
+// Find works like Link.Find
+func (m *MemoryStore) Find(database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error {
+	return m.FindCtx(context.Background(), database, collection, filter, dest, opts...)
+}
+
+// FindCtx works like Link.FindCtx
+func (m *MemoryStore) FindCtx(ctx context.Context, database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error {
+	const routine = "link.Find"
+
+	if dest == nil {
+		return &OpError{Op: opName(routine), Database: database, Collection: collection, Kind: ErrNilDestination}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return opError(routine, database, collection, 1, err)
+	}
+
+	rv := reflect.ValueOf(dest)
+
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return opError(routine, database, collection, 1, mongo.ErrNilDocument)
+	}
+
+	m.mu.RLock()
+
+	defer m.mu.RUnlock()
+
+	docs, err := m.query(database, collection, filter, findOptions(opts))
+
+	if err != nil {
+		return opError(routine, database, collection, 1, err)
+	}
+
+	slice := reflect.MakeSlice(rv.Elem().Type(), len(docs), len(docs))
+
+	for i, doc := range docs {
+		if err := decode(doc, slice.Index(i).Addr().Interface()); err != nil {
+			return opError(routine, database, collection, 1, err)
+		}
+	}
+
+	rv.Elem().Set(slice)
+
+	return nil
+}
+
+// FindOne works like Link.FindOne
+func (m *MemoryStore) FindOne(database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error {
+	return m.FindOneCtx(context.Background(), database, collection, filter, dest, opts...)
+}
+
+// FindOneCtx works like Link.FindOneCtx
+func (m *MemoryStore) FindOneCtx(ctx context.Context, database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error {
+	const routine = "link.FindOne"
+
+	if dest == nil {
+		return &OpError{Op: opName(routine), Database: database, Collection: collection, Kind: ErrNilDestination}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return opError(routine, database, collection, 1, err)
+	}
+
+	m.mu.RLock()
+
+	defer m.mu.RUnlock()
+
+	o := findOptions(opts)
+	o.Limit = 1
+
+	docs, err := m.query(database, collection, filter, o)
+
+	if err != nil {
+		return opError(routine, database, collection, 1, err)
+	}
+
+	if len(docs) == 0 {
+		return opError(routine, database, collection, 1, mongo.ErrNoDocuments)
+	}
+
+	if err := decode(docs[0], dest); err != nil {
+		return opError(routine, database, collection, 1, err)
+	}
+
+	return nil
+}
+
+// insert stores the document, generating an ObjectID if it has no _id. The caller must hold the write lock
+func (m *MemoryStore) insert(database, collection string, document interface{}, index int) (interface{}, error) {
+	doc, err := toDoc(document)
+
+	if err != nil {
+		return nil, err
+	}
+
+	id, ok := docGet(doc, "_id")
+
+	if !ok {
+		id = primitive.NewObjectID()
+		doc = append(bson.D{{Key: "_id", Value: id}}, doc...)
+	}
+
+	docs := m.collection(database, collection)
+
+	for _, d := range docs {
+		if other, _ := docGet(d, "_id"); equal(other, id) {
+			return nil, mongo.WriteException{WriteErrors: mongo.WriteErrors{{Index: index, Code: 11000, Message: "E11000 duplicate key error collection: " + database + "." + collection + " index: _id_"}}}
+		}
+	}
+
+	m.setCollection(database, collection, append(docs, doc))
+
+	return id, nil
+}
+
+// InsertOne works like Link.InsertOne
+func (m *MemoryStore) InsertOne(database, collection string, document interface{}) (string, error) {
+	return m.InsertOneCtx(context.Background(), database, collection, document)
+}
+
+// InsertOneCtx works like Link.InsertOneCtx
+func (m *MemoryStore) InsertOneCtx(ctx context.Context, database, collection string, document interface{}) (string, error) {
+	const routine = "link.InsertOne"
+
+	if err := ctx.Err(); err != nil {
+		return "", opError(routine, database, collection, 1, err)
+	}
+
+	m.mu.Lock()
+
+	defer m.mu.Unlock()
+
+	id, err := m.insert(database, collection, document, 0)
+
+	if err != nil {
+		return "", opError(routine, database, collection, 1, err)
+	}
+
+	oidHex := ""
+
+	if oid, ok := id.(primitive.ObjectID); ok {
+		oidHex = oid.Hex()
+	}
+
+	return oidHex, nil
+}
+
+// InsertMany works like Link.InsertMany
+func (m *MemoryStore) InsertMany(database, collection string, document []interface{}) ([]string, error) {
+	return m.InsertManyCtx(context.Background(), database, collection, document)
+}
+
+// InsertManyCtx works like Link.InsertManyCtx, stopping on the first error, as an ordered insert does
+func (m *MemoryStore) InsertManyCtx(ctx context.Context, database, collection string, document []interface{}) ([]string, error) {
+	const routine = "link.InsertMany"
+
+	if err := ctx.Err(); err != nil {
+		return []string{}, opError(routine, database, collection, 1, err)
+	}
+
+	m.mu.Lock()
+
+	defer m.mu.Unlock()
+
+	var oidHex []string
+
+	for i, d := range document {
+		id, err := m.insert(database, collection, d, i)
+
+		if err != nil {
+			return []string{}, opError(routine, database, collection, 1, err)
+		}
+
+		if oid, ok := id.(primitive.ObjectID); ok {
+			oidHex = append(oidHex, oid.Hex())
+		}
+	}
+
+	return oidHex, nil
+}
+
+// modify applies update, or replacement, to the first limit documents matching filter, or to all if limit is 0
+// It returns the number of matched documents
+func (m *MemoryStore) modify(ctx context.Context, routine, database, collection string, filter, update interface{}, replace bool, limit int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, opError(routine, database, collection, 1, err)
+	}
+
+	if !replace {
+		if err := ValidateUpdate(update); err != nil {
+			return 0, &OpError{Op: opName(routine), Database: database, Collection: collection, Kind: ErrInvalidUpdate, Err: err}
+		}
+	}
+
+	u, err := toDoc(update)
+
+	if err != nil {
+		return 0, opError(routine, database, collection, 1, err)
+	}
+
+	if replace && isUpdateDocument(u) {
+		return 0, opError(routine, database, collection, 1, errors.New("replacement document cannot contain update operators"))
+	}
+
+	m.mu.Lock()
+
+	defer m.mu.Unlock()
+
+	indexes, err := m.filtered(database, collection, filter, limit)
+
+	if err != nil {
+		return 0, opError(routine, database, collection, 1, err)
+	}
+
+	docs := append([]bson.D(nil), m.collection(database, collection)...)
+
+	for _, i := range indexes {
+		var doc bson.D
+
+		if replace {
+			id, _ := docGet(docs[i], "_id")
+
+			doc = append(bson.D{{Key: "_id", Value: id}}, unsetPath(cloneDoc(u), []string{"_id"})...)
+		} else if doc, err = applyUpdate(docs[i], u); err != nil {
+			return 0, opError(routine, database, collection, 1, err)
+		}
+
+		docs[i] = doc
+	}
+
+	m.setCollection(database, collection, docs)
+
+	return int64(len(indexes)), nil
+}
+
+// UpdateOne works like Link.UpdateOne
+func (m *MemoryStore) UpdateOne(database, collection string, filter, update interface{}) (int64, error) {
+	return m.UpdateOneCtx(context.Background(), database, collection, filter, update)
+}
+
+// UpdateOneCtx works like Link.UpdateOneCtx
+func (m *MemoryStore) UpdateOneCtx(ctx context.Context, database, collection string, filter, update interface{}) (int64, error) {
+	return m.modify(ctx, "link.UpdateOne", database, collection, filter, update, false, 1)
+}
+
+// UpdateMany works like Link.UpdateMany
+func (m *MemoryStore) UpdateMany(database, collection string, filter, update interface{}) (int64, error) {
+	return m.UpdateManyCtx(context.Background(), database, collection, filter, update)
+}
+
+// UpdateManyCtx works like Link.UpdateManyCtx
+func (m *MemoryStore) UpdateManyCtx(ctx context.Context, database, collection string, filter, update interface{}) (int64, error) {
+	return m.modify(ctx, "link.UpdateMany", database, collection, filter, update, false, 0)
+}
+
+// ReplaceOne works like Link.ReplaceOne
+func (m *MemoryStore) ReplaceOne(database, collection string, filter, replacement interface{}) (int64, error) {
+	return m.ReplaceOneCtx(context.Background(), database, collection, filter, replacement)
+}
+
+// ReplaceOneCtx works like Link.ReplaceOneCtx
+func (m *MemoryStore) ReplaceOneCtx(ctx context.Context, database, collection string, filter, replacement interface{}) (int64, error) {
+	return m.modify(ctx, "link.ReplaceOne", database, collection, filter, replacement, true, 1)
+}
+
+// remove deletes the first limit documents matching filter, or all if limit is 0
+func (m *MemoryStore) remove(ctx context.Context, routine, database, collection string, filter interface{}, limit int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, opError(routine, database, collection, 1, err)
+	}
+
+	m.mu.Lock()
+
+	defer m.mu.Unlock()
+
+	indexes, err := m.filtered(database, collection, filter, limit)
+
+	if err != nil {
+		return 0, opError(routine, database, collection, 1, err)
+	}
+
+	if len(indexes) == 0 {
+		return 0, nil
+	}
+
+	var kept []bson.D
+
+	docs := m.collection(database, collection)
+
+	for i, next := 0, 0; i < len(docs); i++ {
+		if next < len(indexes) && indexes[next] == i {
+			next++
+
+			continue
+		}
+
+		kept = append(kept, docs[i])
+	}
+
+	m.setCollection(database, collection, kept)
+
+	return int64(len(indexes)), nil
+}
+
+// DeleteOne works like Link.DeleteOne
+func (m *MemoryStore) DeleteOne(database, collection string, filter interface{}) (int64, error) {
+	return m.DeleteOneCtx(context.Background(), database, collection, filter)
+}
+
+// DeleteOneCtx works like Link.DeleteOneCtx
+func (m *MemoryStore) DeleteOneCtx(ctx context.Context, database, collection string, filter interface{}) (int64, error) {
+	return m.remove(ctx, "link.DeleteOne", database, collection, filter, 1)
+}
+
+// DeleteMany works like Link.DeleteMany
+func (m *MemoryStore) DeleteMany(database, collection string, filter interface{}) (int64, error) {
+	return m.DeleteManyCtx(context.Background(), database, collection, filter)
+}
+
+// DeleteManyCtx works like Link.DeleteManyCtx
+func (m *MemoryStore) DeleteManyCtx(ctx context.Context, database, collection string, filter interface{}) (int64, error) {
+	return m.remove(ctx, "link.DeleteMany", database, collection, filter, 0)
+}
+
+// CountDocs works like Link.CountDocs
+func (m *MemoryStore) CountDocs(database, collection string, filter interface{}, opts ...FindOption) (int64, error) {
+	return m.CountDocsCtx(context.Background(), database, collection, filter, opts...)
+}
+
+// CountDocsCtx works like Link.CountDocsCtx
+func (m *MemoryStore) CountDocsCtx(ctx context.Context, database, collection string, filter interface{}, opts ...FindOption) (int64, error) {
+	const routine = "link.CountDocs"
+
+	if err := ctx.Err(); err != nil {
+		return 0, opError(routine, database, collection, 1, err)
+	}
+
+	m.mu.RLock()
+
+	defer m.mu.RUnlock()
+
+	o := findOptions(opts)
+
+	docs, err := m.query(database, collection, filter, &FindOptions{Skip: o.Skip, Limit: o.Limit})
+
+	if err != nil {
+		return 0, opError(routine, database, collection, 1, err)
+	}
+
+	return int64(len(docs)), nil
+}
+
+// Paginate works like Link.Paginate
+func (m *MemoryStore) Paginate(database, collection string, filter interface{}, req PageRequest, dest interface{}) (*Page, error) {
+	return m.PaginateCtx(context.Background(), database, collection, filter, req, dest)
+}
+
+// PaginateCtx works like Link.PaginateCtx
+func (m *MemoryStore) PaginateCtx(ctx context.Context, database, collection string, filter interface{}, req PageRequest, dest interface{}) (*Page, error) {
+	return paginate(ctx, m, database, collection, filter, req, dest)
+}
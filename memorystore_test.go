@@ -0,0 +1,163 @@
+package mongohelper
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMemoryStore(t *testing.T) {
+	m := MemoryStoreNew()
+
+	var a []interface{}
+
+	for i := 0; i < 10; i++ {
+		a = append(a, testDocStruct{Name: fmt.Sprintf("test #%d", i), N: i + 1})
+	}
+
+	if ids, err := m.InsertMany(testDB, testCollection, a); err != nil || len(ids) != 10 {
+		t.Fatalf("expected 10 generated ids, got %v ( %v )", ids, err)
+	}
+
+	filters := map[string]struct {
+		filter interface{}
+		want   int64
+	}{
+		"all":      {bson.M{}, 10},
+		"equality": {bson.M{"n": 3}, 1},
+		"range":    {bson.M{"n": bson.M{"$gte": 2, "$lt": 5}}, 3},
+		"in":       {bson.M{"name": bson.M{"$in": bson.A{"test #1", "test #9", "nope"}}}, 2},
+		"or":       {bson.M{"$or": bson.A{bson.M{"n": 1}, bson.M{"n": bson.M{"$gt": 9}}}}, 2},
+		"and":      {bson.D{{Key: "$and", Value: bson.A{bson.M{"n": bson.M{"$gt": 1}}, bson.M{"n": bson.M{"$lt": 3}}}}}, 1},
+		"exists":   {bson.M{"xyz": bson.M{"$exists": false}}, 10},
+	}
+
+	for name, f := range filters {
+		if n, err := m.CountDocs(testDB, testCollection, f.filter); err != nil || n != f.want {
+			t.Errorf("%s: expected %d documents, got %d ( %v )", name, f.want, n, err)
+		}
+	}
+
+	if n, err := m.UpdateMany(testDB, testCollection, bson.M{"n": bson.M{"$lte": 3}}, bson.M{"$set": bson.M{"xyz.abc": "set"}, "$inc": bson.M{"n": 100}, "$push": bson.M{"tags": "pushed"}}); err != nil || n != 3 {
+		t.Fatalf("expected 3 updated documents, got %d ( %v )", n, err)
+	}
+
+	var updated []bson.M
+
+	if err := m.Find(testDB, testCollection, bson.M{"xyz.abc": "set", "tags": "pushed", "n": bson.M{"$gte": 100}}, &updated); err != nil || len(updated) != 3 {
+		t.Fatalf("expected 3 updated documents, got %v ( %v )", updated, err)
+	}
+
+	if n, err := m.UpdateOne(testDB, testCollection, bson.M{"n": 101}, bson.M{"$unset": bson.M{"xyz": ""}}); err != nil || n != 1 {
+		t.Errorf("expected 1 updated document, got %d ( %v )", n, err)
+	}
+
+	if n, _ := m.CountDocs(testDB, testCollection, bson.M{"xyz": bson.M{"$exists": true}}); n != 2 {
+		t.Errorf("expected $unset to remove the field, %d documents still have it", n)
+	}
+
+	if _, err := m.UpdateOne(testDB, testCollection, bson.M{}, bson.M{"name": "replacement"}); err == nil {
+		t.Error("expected an error for an update without operators")
+	}
+
+	if n, err := m.DeleteMany(testDB, testCollection, bson.M{"n": bson.M{"$gte": 100}}); err != nil || n != 3 {
+		t.Errorf("expected 3 deleted documents, got %d ( %v )", n, err)
+	}
+
+	var x testDocStruct
+
+	if err := m.FindOne(testDB, testCollection, bson.M{"n": 101}, &x); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMemoryStore_repository(t *testing.T) {
+	r := RepositoryNew[testDocStruct](MemoryStoreNew(), testDB, testCollection)
+
+	id, err := r.Insert(testDocStruct{Name: "repository", N: 1})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x, err := r.FindByID(id)
+
+	if err != nil || x.Name != "repository" {
+		t.Fatalf("expected to find the inserted document, got %+v ( %v )", x, err)
+	}
+
+	if _, err := r.Insert(x); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected ErrDuplicateKey, got %v", err)
+	}
+
+	x.N = 2
+
+	if n, err := r.Replace(x); err != nil || n != 1 {
+		t.Errorf("expected 1 replaced document, got %d ( %v )", n, err)
+	}
+
+	if a, err := r.Find(bson.M{"n": 2}); err != nil || len(a) != 1 || a[0].ID != x.ID {
+		t.Errorf("expected the replaced document, got %v ( %v )", a, err)
+	}
+
+	if n, err := r.Delete(x); err != nil || n != 1 {
+		t.Errorf("expected 1 deleted document, got %d ( %v )", n, err)
+	}
+
+	if n, err := r.Count(nil); err != nil || n != 0 {
+		t.Errorf("expected an empty collection, got %d ( %v )", n, err)
+	}
+}
+
+func TestMemoryStore_updateOperators(t *testing.T) {
+	m := MemoryStoreNew()
+
+	if _, err := m.InsertOne(testDB, testCollection, bson.M{"_id": "x", "nested": bson.A{bson.A{int32(5)}}, "n": int32(math.MaxInt32), "m": int32(math.MaxInt32)}); err != nil {
+		t.Fatal(err)
+	}
+
+	update := bson.M{
+		"$addToSet": bson.M{"nested": int32(5)},
+		"$inc":      bson.M{"n": int32(1)},
+		"$mul":      bson.M{"m": int32(2)},
+	}
+
+	if n, err := m.UpdateOne(testDB, testCollection, bson.M{"_id": "x"}, update); err != nil || n != 1 {
+		t.Fatalf("expected 1 updated document, got %d ( %v )", n, err)
+	}
+
+	var doc bson.M
+
+	if err := m.FindOne(testDB, testCollection, bson.M{"_id": "x"}, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if nested, _ := doc["nested"].(bson.A); len(nested) != 2 {
+		t.Errorf("expected $addToSet to compare whole elements, got %v", doc["nested"])
+	}
+
+	if n, ok := doc["n"].(int64); !ok || n != math.MaxInt32+1 {
+		t.Errorf("expected $inc to promote an overflowing int32 to int64, got %T %v", doc["n"], doc["n"])
+	}
+
+	if n, ok := doc["m"].(int64); !ok || n != 2*math.MaxInt32 {
+		t.Errorf("expected $mul to promote an overflowing int32 to int64, got %T %v", doc["m"], doc["m"])
+	}
+
+	if _, err := m.UpdateOne(testDB, testCollection, bson.M{"_id": "x"}, bson.M{"$addToSet": bson.M{"nested": bson.A{int32(5)}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	doc = nil
+
+	if err := m.FindOne(testDB, testCollection, bson.M{"_id": "x"}, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if nested, _ := doc["nested"].(bson.A); len(nested) != 2 {
+		t.Errorf("expected an equal array element not to be added again, got %v", doc["nested"])
+	}
+}
@@ -0,0 +1,65 @@
+package mongohelper
+
+import (
+	"errors"
+	"time"
+)
+
+// Metrics receives measures of operations and connection health
+// Implementations must be safe for concurrent use. See MetricsRegistry for an in-process implementation
+type Metrics interface {
+	// ObserveOperation is called once per operation, after all its attempts, with one of the Outcome* values
+	ObserveOperation(kind OpKind, database, collection, outcome string, d time.Duration)
+	// ObserveReconnect is called after each reconnection attempt, not for the first connection; err is nil on success
+	ObserveReconnect(attempt uint, err error)
+	// ObservePoolEvent is called for each driver connection pool event, like event.GetSucceeded for checkouts
+	ObservePoolEvent(eventType, address string)
+	// ObservePing is called after each ping; err is nil on success
+	ObservePing(d time.Duration, err error)
+}
+
+// Outcomes reported to Metrics.ObserveOperation
+const (
+	OutcomeSuccess         = "success"
+	OutcomeNotFound        = "not_found"
+	OutcomeDuplicateKey    = "duplicate_key"
+	OutcomeTimeout         = "timeout"
+	OutcomeNotConnected    = "not_connected"
+	OutcomeReconnectFailed = "reconnect_failed"
+	OutcomeError           = "error"
+)
+
+// outcome classifies err into one of the Outcome* values
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, ErrNotFound):
+		return OutcomeNotFound
+	case errors.Is(err, ErrDuplicateKey):
+		return OutcomeDuplicateKey
+	case errors.Is(err, ErrTimeout):
+		return OutcomeTimeout
+	case errors.Is(err, ErrReconnectFailed):
+		return OutcomeReconnectFailed
+	case errors.Is(err, ErrNotConnected):
+		return OutcomeNotConnected
+	}
+
+	return OutcomeError
+}
+
+// NopMetrics discards every measure
+type NopMetrics struct{}
+
+// ObserveOperation discards the measure
+func (NopMetrics) ObserveOperation(OpKind, string, string, string, time.Duration) {}
+
+// ObserveReconnect discards the measure
+func (NopMetrics) ObserveReconnect(uint, error) {}
+
+// ObservePoolEvent discards the measure
+func (NopMetrics) ObservePoolEvent(string, string) {}
+
+// ObservePing discards the measure
+func (NopMetrics) ObservePing(time.Duration, error) {}
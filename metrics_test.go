@@ -0,0 +1,90 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMetricsRegistry(t *testing.T) {
+	r := MetricsRegistryNew(10*time.Millisecond, time.Millisecond)
+
+	r.ObserveOperation(OpFind, testDB, testCollection, OutcomeSuccess, 500*time.Microsecond)
+	r.ObserveOperation(OpFind, testDB, testCollection, OutcomeSuccess, 5*time.Millisecond)
+	r.ObserveOperation(OpFind, testDB, testCollection, OutcomeSuccess, time.Second)
+	r.ObserveReconnect(1, errors.New("unreachable"))
+	r.ObserveReconnect(2, nil)
+	r.ObservePoolEvent("ConnectionCheckedOut", "127.0.0.1:27017")
+	r.ObservePing(time.Millisecond, nil)
+
+	s := r.Snapshot()
+
+	h := s.Operations[OperationLabels{Kind: OpFind, Database: testDB, Collection: testCollection, Outcome: OutcomeSuccess}]
+
+	if h.Count != 3 || h.Counts[0] != 1 || h.Counts[1] != 1 || h.Counts[2] != 1 {
+		t.Errorf("expected one observation per bucket, got %+v", h)
+	}
+
+	if s.ReconnectAttempts != 2 || s.ReconnectSuccesses != 1 {
+		t.Errorf("expected 2 attempts and 1 success, got %d and %d", s.ReconnectAttempts, s.ReconnectSuccesses)
+	}
+
+	if s.PoolEvents["ConnectionCheckedOut"] != 1 || s.Pings.Count != 1 || s.PingFailures != 0 {
+		t.Errorf("unexpected snapshot %+v", s)
+	}
+
+	// snapshots are copies
+	h.Counts[0] = 100
+
+	if r.Snapshot().Operations[OperationLabels{Kind: OpFind, Database: testDB, Collection: testCollection, Outcome: OutcomeSuccess}].Counts[0] != 1 {
+		t.Error("expected the snapshot to be independent from the registry")
+	}
+}
+
+func TestLink_metrics(t *testing.T) {
+	r := MetricsRegistryNew()
+
+	l := linkNew(*NewOptions("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=50", WithMetrics(r)))
+	l.options.connTimeout = 50 * time.Millisecond
+	l.options.reconnectionInterval = 10 * time.Millisecond
+
+	if err := l.connectCtx(context.Background(), false); err == nil {
+		t.Fatal("expected connection to fail")
+	}
+
+	if s := r.Snapshot(); s.ReconnectAttempts != 0 {
+		t.Errorf("expected the first connection not to count as a reconnection, got %d attempts", s.ReconnectAttempts)
+	}
+
+	// simulates a client closed underneath the link, so the operation triggers a reconnection
+	if err := l.currentClient().Disconnect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := l.CountDocs(testDB, testCollection, bson.M{}); err == nil {
+		t.Fatal("expected the count to fail")
+	}
+
+	s := r.Snapshot()
+
+	if s.ReconnectAttempts != 1 || s.ReconnectSuccesses != 0 {
+		t.Errorf("expected 1 failed attempt, got %d attempts and %d successes", s.ReconnectAttempts, s.ReconnectSuccesses)
+	}
+
+	if s.Pings.Count == 0 || s.PingFailures != s.Pings.Count {
+		t.Errorf("expected only failed pings, got %d of %d", s.PingFailures, s.Pings.Count)
+	}
+
+	if len(s.Operations) != 1 {
+		t.Fatalf("expected one operation series, got %v", s.Operations)
+	}
+
+	for labels, h := range s.Operations {
+		if labels.Kind != OpCountDocs || labels.Outcome == OutcomeSuccess || h.Count != 1 {
+			t.Errorf("unexpected series %+v: %+v", labels, h)
+		}
+	}
+}
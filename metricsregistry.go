@@ -0,0 +1,178 @@
+package mongohelper
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds used by MetricsRegistryNew() when none are given
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond,
+	500 * time.Millisecond, time.Second, 5 * time.Second, 10 * time.Second,
+}
+
+// Histogram counts observations by latency bucket
+type Histogram struct {
+	// Bounds are the buckets upper bounds, in increasing order
+	Bounds []time.Duration
+	// Counts has one more element than Bounds: Counts[i] is the number of observations <= Bounds[i] and
+	// > Bounds[i-1]; the last one counts the observations above every bound
+	Counts []uint64
+	// Count is the total number of observations
+	Count uint64
+	// Sum is the total of the observed durations
+	Sum time.Duration
+}
+
+func newHistogram(bounds []time.Duration) *Histogram {
+	return &Histogram{Bounds: bounds, Counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	h.Counts[sort.Search(len(h.Bounds), func(i int) bool { return d <= h.Bounds[i] })]++
+	h.Count++
+	h.Sum += d
+}
+
+func (h *Histogram) clone() Histogram {
+	c := *h
+	c.Counts = append([]uint64(nil), h.Counts...)
+
+	return c
+}
+
+// OperationLabels identifies a series of operation measures
+type OperationLabels struct {
+	Kind       OpKind
+	Database   string
+	Collection string
+	Outcome    string
+}
+
+// MetricsSnapshot is a consistent copy of the measures kept by a MetricsRegistry
+type MetricsSnapshot struct {
+	// Operations holds the latency histogram, that also counts operations, for each label set
+	Operations map[OperationLabels]Histogram
+	// ReconnectAttempts counts every reconnection attempt, successful or not. The first connection is not counted
+	ReconnectAttempts uint64
+	// ReconnectSuccesses counts the successful reconnection attempts
+	ReconnectSuccesses uint64
+	// PoolEvents counts the connection pool events by type. event.GetSucceeded is a checkout
+	PoolEvents map[string]uint64
+	// Pings is the latency histogram of pings
+	Pings Histogram
+	// PingFailures counts the failed pings
+	PingFailures uint64
+}
+
+// MetricsRegistry is an in-process Metrics implementation, that keeps everything in memory
+// Its Snapshot() can be exported to any monitoring system, or checked by tests
+type MetricsRegistry struct {
+	mu                 sync.Mutex
+	bounds             []time.Duration
+	operations         map[OperationLabels]*Histogram
+	reconnectAttempts  uint64
+	reconnectSuccesses uint64
+	poolEvents         map[string]uint64
+	pings              *Histogram
+	pingFailures       uint64
+}
+
+// MetricsRegistryNew returns an empty registry whose histograms use the given bucket bounds
+// If no bound is given, DefaultLatencyBuckets is used
+func MetricsRegistryNew(bounds ...time.Duration) *MetricsRegistry {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBuckets
+	}
+
+	bounds = append([]time.Duration(nil), bounds...)
+
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	return &MetricsRegistry{
+		bounds:     bounds,
+		operations: make(map[OperationLabels]*Histogram),
+		poolEvents: make(map[string]uint64),
+		pings:      newHistogram(bounds),
+	}
+}
+
+// ObserveOperation records an operation latency under its labels
+func (r *MetricsRegistry) ObserveOperation(kind OpKind, database, collection, outcome string, d time.Duration) {
+	r.mu.Lock()
+
+	defer r.mu.Unlock()
+
+	labels := OperationLabels{Kind: kind, Database: database, Collection: collection, Outcome: outcome}
+
+	h, ok := r.operations[labels]
+
+	if !ok {
+		h = newHistogram(r.bounds)
+		r.operations[labels] = h
+	}
+
+	h.observe(d)
+}
+
+// ObserveReconnect counts a (re)connection attempt
+func (r *MetricsRegistry) ObserveReconnect(_ uint, err error) {
+	r.mu.Lock()
+
+	defer r.mu.Unlock()
+
+	r.reconnectAttempts++
+
+	if err == nil {
+		r.reconnectSuccesses++
+	}
+}
+
+// ObservePoolEvent counts a connection pool event
+func (r *MetricsRegistry) ObservePoolEvent(eventType, _ string) {
+	r.mu.Lock()
+
+	defer r.mu.Unlock()
+
+	r.poolEvents[eventType]++
+}
+
+// ObservePing records a ping latency
+func (r *MetricsRegistry) ObservePing(d time.Duration, err error) {
+	r.mu.Lock()
+
+	defer r.mu.Unlock()
+
+	r.pings.observe(d)
+
+	if err != nil {
+		r.pingFailures++
+	}
+}
+
+// Snapshot returns a copy of every measure
+func (r *MetricsRegistry) Snapshot() MetricsSnapshot {
+	r.mu.Lock()
+
+	defer r.mu.Unlock()
+
+	s := MetricsSnapshot{
+		Operations:         make(map[OperationLabels]Histogram, len(r.operations)),
+		ReconnectAttempts:  r.reconnectAttempts,
+		ReconnectSuccesses: r.reconnectSuccesses,
+		PoolEvents:         make(map[string]uint64, len(r.poolEvents)),
+		Pings:              r.pings.clone(),
+		PingFailures:       r.pingFailures,
+	}
+
+	for labels, h := range r.operations {
+		s.Operations[labels] = h.clone()
+	}
+
+	for t, n := range r.poolEvents {
+		s.PoolEvents[t] = n
+	}
+
+	return s
+}
@@ -0,0 +1,413 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultMigrationsCollection keeps the applied migrations and the lock, when MigratorOptions.Collection isn't set
+const DefaultMigrationsCollection = "_migrations"
+
+// migrationLockID is the _id of the lock document, kept along with the applied migrations
+const migrationLockID = "lock"
+
+// Migration is a versioned change of data or schema
+type Migration struct {
+	// Version orders the migrations. It must be unique and greater than zero, like 20260101120000
+	Version     uint64
+	Description string
+	// Up applies the migration
+	Up func(ctx context.Context, link *Link) error
+	// Down reverts it. A nil Down makes the migration irreversible
+	Down func(ctx context.Context, link *Link) error
+}
+
+// MigrationStatus describes a migration, registered or applied
+type MigrationStatus struct {
+	Version     uint64
+	Description string
+	// Applied tells if the migration is recorded as applied, at AppliedAt
+	Applied   bool
+	AppliedAt time.Time
+	// Registered is false for applied migrations that aren't registered anymore
+	Registered bool
+}
+
+// MigratorOptions tunes a Migrator
+type MigratorOptions struct {
+	// Collection keeps the applied migrations and the lock. Defaults to DefaultMigrationsCollection
+	Collection string
+	// LockTTL is how long the lock survives an instance that crashed. It's renewed while migrations run. Defaults to 5 minutes
+	LockTTL time.Duration
+	// LockWait is how long to wait for the lock held by another instance. Defaults to LockTTL
+	LockWait time.Duration
+}
+
+// Migrator applies and reverts migrations, recording the applied versions in a collection
+// A lock document in the same collection makes concurrent instances run the migrations one at a time
+type Migrator struct {
+	link       *Link
+	store      Store
+	database   string
+	collection string
+	lockTTL    time.Duration
+	lockWait   time.Duration
+	// owner identifies this instance in the lock document
+	owner string
+	// mu guards migrations
+	mu         sync.Mutex
+	migrations []Migration
+}
+
+// MigratorNew returns a Migrator that records the migrations in the given database of link
+func MigratorNew(link *Link, database string, opts MigratorOptions) *Migrator {
+	if opts.Collection == "" {
+		opts.Collection = DefaultMigrationsCollection
+	}
+
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = 5 * time.Minute
+	}
+
+	if opts.LockWait <= 0 {
+		opts.LockWait = opts.LockTTL
+	}
+
+	host, _ := os.Hostname()
+
+	return &Migrator{
+		link:       link,
+		store:      link,
+		database:   database,
+		collection: opts.Collection,
+		lockTTL:    opts.LockTTL,
+		lockWait:   opts.LockWait,
+		owner:      fmt.Sprintf("%s:%d:%s", host, os.Getpid(), primitive.NewObjectID().Hex()),
+	}
+}
+
+// Register adds migrations. It fails, registering none, on a zero or repeated version, or a nil Up
+func (m *Migrator) Register(migrations ...Migration) error {
+	m.mu.Lock()
+
+	defer m.mu.Unlock()
+
+	versions := make(map[uint64]bool, len(m.migrations)+len(migrations))
+
+	for _, x := range m.migrations {
+		versions[x.Version] = true
+	}
+
+	for _, x := range migrations {
+		switch {
+		case x.Version == 0:
+			return fmt.Errorf("%w: version must be greater than zero", ErrInvalidMigration)
+		case versions[x.Version]:
+			return fmt.Errorf("%w: version %d already registered", ErrInvalidMigration, x.Version)
+		case x.Up == nil:
+			return fmt.Errorf("%w: version %d has no Up", ErrInvalidMigration, x.Version)
+		}
+
+		versions[x.Version] = true
+	}
+
+	m.migrations = append(m.migrations, migrations...)
+
+	sort.Slice(m.migrations, func(i, j int) bool { return m.migrations[i].Version < m.migrations[j].Version })
+
+	return nil
+}
+
+// registered returns a copy of the migrations, in ascending version order
+func (m *Migrator) registered() []Migration {
+	m.mu.Lock()
+
+	defer m.mu.Unlock()
+
+	return append([]Migration(nil), m.migrations...)
+}
+
+// migrationDoc records an applied migration
+type migrationDoc struct {
+	Version     uint64    `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedAt"`
+}
+
+// applied returns the recorded migrations by version
+func (m *Migrator) applied(ctx context.Context) (map[uint64]migrationDoc, error) {
+	var a []migrationDoc
+
+	if err := m.store.FindCtx(ctx, m.database, m.collection, bson.M{"_id": bson.M{"$ne": migrationLockID}}, &a); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[uint64]migrationDoc, len(a))
+
+	for _, d := range a {
+		applied[d.Version] = d
+	}
+
+	return applied, nil
+}
+
+// Status returns every registered or applied migration, in ascending version order
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var a []MigrationStatus
+
+	for _, x := range m.registered() {
+		d, ok := applied[x.Version]
+
+		a = append(a, MigrationStatus{Version: x.Version, Description: x.Description, Applied: ok, AppliedAt: d.AppliedAt, Registered: true})
+
+		delete(applied, x.Version)
+	}
+
+	for _, d := range applied {
+		a = append(a, MigrationStatus{Version: d.Version, Description: d.Description, Applied: true, AppliedAt: d.AppliedAt})
+	}
+
+	sort.Slice(a, func(i, j int) bool { return a[i].Version < a[j].Version })
+
+	return a, nil
+}
+
+// MigrateUp applies every registered migration not applied yet, in ascending version order, including the ones
+// older than the last applied. It stops on the first failure. It returns the versions applied
+// It waits for the lock while another instance is migrating, up to MigratorOptions.LockWait
+func (m *Migrator) MigrateUp(ctx context.Context) ([]uint64, error) {
+	var done []uint64
+
+	err := m.locked(ctx, func(ctx context.Context, held func() bool) error {
+		applied, err := m.applied(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		for _, x := range m.registered() {
+			if _, ok := applied[x.Version]; ok {
+				continue
+			}
+
+			if !held() {
+				return ErrMigrationLocked
+			}
+
+			if err := m.run(ctx, x, "up", x.Up); err != nil {
+				return err
+			}
+
+			if _, err := m.store.InsertOneCtx(ctx, m.database, m.collection, migrationDoc{Version: x.Version, Description: x.Description, AppliedAt: time.Now().UTC()}); err != nil {
+				return &MigrationError{Version: x.Version, Description: x.Description, Err: err}
+			}
+
+			done = append(done, x.Version)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// MigrateDown reverts the applied migrations whose versions are greater than to, in descending version order
+// Nothing is reverted if one of them is irreversible or isn't registered anymore. It returns the versions reverted
+func (m *Migrator) MigrateDown(ctx context.Context, to uint64) ([]uint64, error) {
+	var done []uint64
+
+	err := m.locked(ctx, func(ctx context.Context, held func() bool) error {
+		applied, err := m.applied(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		registered := make(map[uint64]Migration)
+
+		for _, x := range m.registered() {
+			registered[x.Version] = x
+		}
+
+		var revert []Migration
+
+		for v, d := range applied {
+			if v <= to {
+				continue
+			}
+
+			x, ok := registered[v]
+
+			if !ok || x.Down == nil {
+				return &MigrationError{Version: v, Description: d.Description, Err: ErrIrreversibleMigration}
+			}
+
+			revert = append(revert, x)
+		}
+
+		sort.Slice(revert, func(i, j int) bool { return revert[i].Version > revert[j].Version })
+
+		for _, x := range revert {
+			if !held() {
+				return ErrMigrationLocked
+			}
+
+			if err := m.run(ctx, x, "down", x.Down); err != nil {
+				return err
+			}
+
+			if _, err := m.store.DeleteOneCtx(ctx, m.database, m.collection, bson.M{"_id": x.Version}); err != nil {
+				return &MigrationError{Version: x.Version, Description: x.Description, Err: err}
+			}
+
+			done = append(done, x.Version)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// run calls fn, the Up or Down of x, logging the outcome
+func (m *Migrator) run(ctx context.Context, x Migration, direction string, fn func(ctx context.Context, link *Link) error) error {
+	const routine = "migrator.run"
+
+	start := time.Now()
+
+	if err := fn(ctx, m.link); err != nil {
+		m.link.logger().Error("migration failed", Field{FieldRoutine, routine}, Field{"version", x.Version}, Field{"direction", direction}, Field{FieldError, err})
+
+		return &MigrationError{Version: x.Version, Description: x.Description, Err: err}
+	}
+
+	m.link.logger().Info("migration done", Field{FieldRoutine, routine}, Field{"version", x.Version}, Field{"direction", direction}, Field{FieldDuration, time.Since(start)})
+
+	return nil
+}
+
+// migrationLock is the lock document
+type migrationLock struct {
+	ID         string    `bson:"_id"`
+	Owner      string    `bson:"owner"`
+	AcquiredAt time.Time `bson:"acquiredAt"`
+	ExpiresAt  time.Time `bson:"expiresAt"`
+}
+
+// locked runs fn holding the lock, that's renewed meanwhile. held reports if the lock is still held
+func (m *Migrator) locked(ctx context.Context, fn func(ctx context.Context, held func() bool) error) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+
+	var lost int32
+
+	hctx, cancel := context.WithCancel(ctx)
+
+	heartbeat := make(chan struct{})
+
+	go func() {
+		defer close(heartbeat)
+
+		t := time.NewTicker(m.lockTTL / 3)
+
+		defer t.Stop()
+
+		for {
+			select {
+			case <-hctx.Done():
+				return
+			case <-t.C:
+			}
+
+			n, err := m.store.UpdateOneCtx(hctx, m.database, m.collection, bson.M{"_id": migrationLockID, "owner": m.owner}, bson.M{"$set": bson.M{"expiresAt": time.Now().UTC().Add(m.lockTTL)}})
+
+			// a failed renewal may still succeed next time, before the lock expires
+			if err == nil && n == 0 {
+				atomic.StoreInt32(&lost, 1)
+
+				return
+			}
+		}
+	}()
+
+	err := fn(ctx, func() bool { return atomic.LoadInt32(&lost) == 0 })
+
+	cancel()
+
+	<-heartbeat
+
+	m.unlock()
+
+	return err
+}
+
+// lock acquires the lock document, waiting for another instance to release it, or for it to expire
+func (m *Migrator) lock(ctx context.Context) error {
+	deadline := time.Now().Add(m.lockWait)
+
+	for {
+		now := time.Now().UTC()
+
+		_, err := m.store.InsertOneCtx(ctx, m.database, m.collection, migrationLock{ID: migrationLockID, Owner: m.owner, AcquiredAt: now, ExpiresAt: now.Add(m.lockTTL)})
+
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, ErrDuplicateKey) {
+			return err
+		}
+
+		// the instance holding it crashed
+		n, err := m.store.UpdateOneCtx(ctx, m.database, m.collection,
+			bson.M{"_id": migrationLockID, "expiresAt": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"owner": m.owner, "acquiredAt": now, "expiresAt": now.Add(m.lockTTL)}})
+
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			m.link.logger().Warn("expired migration lock taken over", Field{FieldRoutine, "migrator.lock"})
+
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return ErrMigrationLocked
+		}
+
+		if err := sleep(ctx, time.Second); err != nil {
+			return err
+		}
+	}
+}
+
+// unlock releases the lock, even if the migration context is done
+func (m *Migrator) unlock() {
+	err := m.link.release(func(ctx context.Context) error {
+		_, err := m.store.DeleteOneCtx(ctx, m.database, m.collection, bson.M{"_id": migrationLockID, "owner": m.owner})
+
+		return err
+	})
+
+	if err != nil {
+		m.link.logger().Error("migration lock not released", Field{FieldRoutine, "migrator.unlock"}, Field{FieldError, err})
+	}
+}
@@ -0,0 +1,172 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// memoryMigrator returns a Migrator that records the migrations in store
+func memoryMigrator(store Store, opts MigratorOptions) *Migrator {
+	m := MigratorNew(linkNew(*NewOptions(testConnectionString)), testDB, opts)
+
+	m.store = store
+
+	return m
+}
+
+func TestMigrator(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		trail []string
+	)
+
+	step := func(s string, err error) func(context.Context, *Link) error {
+		return func(context.Context, *Link) error {
+			mu.Lock()
+
+			defer mu.Unlock()
+
+			trail = append(trail, s)
+
+			return err
+		}
+	}
+
+	store := MemoryStoreNew()
+
+	m := memoryMigrator(store, MigratorOptions{})
+
+	if err := m.Register(Migration{Version: 3, Description: "three", Up: step("up 3", nil), Down: step("down 3", nil)}, Migration{Version: 1, Description: "one", Up: step("up 1", nil)}); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := []Migration{{Version: 0, Up: step("", nil)}, {Version: 3, Up: step("", nil)}, {Version: 4}}
+
+	for _, x := range invalid {
+		if err := m.Register(x); !errors.Is(err, ErrInvalidMigration) {
+			t.Errorf("expected ErrInvalidMigration for %+v, got %v", x, err)
+		}
+	}
+
+	ctx := context.Background()
+
+	if done, err := m.MigrateUp(ctx); err != nil || len(done) != 2 || done[0] != 1 || done[1] != 3 {
+		t.Fatalf("expected versions 1 and 3 applied, got %v ( %v )", done, err)
+	}
+
+	// an older migration registered later is applied too
+	_ = m.Register(Migration{Version: 2, Description: "two", Up: step("up 2", nil), Down: step("down 2", nil)}, Migration{Version: 4, Description: "four", Up: step("up 4", errors.New("boom"))})
+
+	done, err := m.MigrateUp(ctx)
+
+	var me *MigrationError
+
+	if !errors.As(err, &me) || me.Version != 4 || len(done) != 1 || done[0] != 2 {
+		t.Fatalf("expected version 2 applied and version 4 failed, got %v ( %v )", done, err)
+	}
+
+	status, err := m.Status(ctx)
+
+	if err != nil || len(status) != 4 {
+		t.Fatalf("expected 4 migrations, got %+v ( %v )", status, err)
+	}
+
+	for i, applied := range []bool{true, true, true, false} {
+		if s := status[i]; s.Version != uint64(i+1) || s.Applied != applied || !s.Registered || (applied && s.AppliedAt.IsZero()) {
+			t.Errorf("unexpected status %+v", s)
+		}
+	}
+
+	if done, err := m.MigrateDown(ctx, 0); !errors.Is(err, ErrIrreversibleMigration) || len(done) != 0 {
+		t.Errorf("expected nothing reverted, as version 1 is irreversible, got %v ( %v )", done, err)
+	}
+
+	if done, err := m.MigrateDown(ctx, 1); err != nil || len(done) != 2 || done[0] != 3 || done[1] != 2 {
+		t.Errorf("expected versions 3 and 2 reverted, got %v ( %v )", done, err)
+	}
+
+	want := []string{"up 1", "up 3", "up 2", "up 4", "down 3", "down 2"}
+
+	if len(trail) != len(want) {
+		t.Fatalf("expected %v, got %v", want, trail)
+	}
+
+	for i := range want {
+		if trail[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, trail)
+		}
+	}
+
+	if n, _ := store.CountDocs(testDB, DefaultMigrationsCollection, bson.M{}); n != 1 {
+		t.Errorf("expected only version 1 recorded, and the lock released, got %d documents", n)
+	}
+}
+
+func TestMigrator_lock(t *testing.T) {
+	store := MemoryStoreNew()
+
+	runs := 0
+
+	up := func(context.Context, *Link) error {
+		runs++
+
+		time.Sleep(100 * time.Millisecond)
+
+		return nil
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 2; i++ {
+		m := memoryMigrator(store, MigratorOptions{LockTTL: time.Minute, LockWait: 3 * time.Second})
+
+		_ = m.Register(Migration{Version: 1, Up: up})
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := m.MigrateUp(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if runs != 1 {
+		t.Errorf("expected the migration to run once, got %d runs", runs)
+	}
+
+	// a lock held by another instance
+	now := time.Now().UTC()
+
+	if _, err := store.InsertOne(testDB, DefaultMigrationsCollection, migrationLock{ID: migrationLockID, Owner: "other", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := memoryMigrator(store, MigratorOptions{LockTTL: time.Minute, LockWait: time.Millisecond})
+
+	if _, err := m.MigrateUp(context.Background()); !errors.Is(err, ErrMigrationLocked) {
+		t.Errorf("expected ErrMigrationLocked, got %v", err)
+	}
+
+	// an expired lock, of an instance that crashed
+	if _, err := store.UpdateOne(testDB, DefaultMigrationsCollection, bson.M{"_id": migrationLockID}, bson.M{"$set": bson.M{"expiresAt": now.Add(-time.Second)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.MigrateUp(context.Background()); err != nil {
+		t.Errorf("expected the expired lock to be taken over, got %v", err)
+	}
+
+	if n, _ := store.CountDocs(testDB, DefaultMigrationsCollection, bson.M{"_id": migrationLockID}); n != 0 {
+		t.Error("expected the lock to be released")
+	}
+}
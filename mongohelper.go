@@ -19,19 +19,27 @@ const (
 
 // New returns an instance of mongohelper, ugins given options
 // It the connection conditions are ok, it comes alerady connected and tested with .Ping()
-// You may prefer to create the options with .OptionsNew() function
+// You may prefer to create the options with .NewOptions() function
+// The options are checked with .Validate() before connecting
+// If the options enable it, a supervisor routine keeps checking the connection until .Disconnect() is called
 func New(opts *Options) (*Link, error) {
 	if opts == nil {
 		return nil, fmt.Errorf("uninitialized options")
 	}
 
-	link := Link{
-		options: *opts,
+	if err := opts.Validate(); err != nil {
+		return nil, err
 	}
 
+	link := linkNew(*opts)
+
 	if err := link.connect(); err != nil {
 		return nil, err
 	}
 
-	return &link, nil
+	if opts.supervisorInterval > 0 {
+		link.supervise(opts.supervisorInterval)
+	}
+
+	return link, nil
 }
@@ -12,24 +12,197 @@ type Options struct {
 	appName string
 	// URI with directives for mongodb connection
 	connString string
-	// printLogMessages if true allow the engine to print log messages
-	printLogMessages bool
-	// connTimeoutSeconds is quite obvious
-	connTimeoutSeconds uint
-	// execTimeoutSeconds equals to how much time the engine waits before return an error
-	execTimeoutSeconds uint
+	// logger receives the engine log messages
+	logger Logger
+	// connTimeout is quite obvious
+	connTimeout time.Duration
+	// execTimeout equals to how much time the engine waits before return an error
+	execTimeout time.Duration
 	// InsistOnFail Is can't connect on first attempt, if should retry
 	reconnectionInsistOnFail bool
-	// SecondsBetweenAttempts Seconds btween each connection attempt
-	reconnectionSecondsBetweenAttempts uint
+	// reconnectionInterval time between each connection attempt
+	reconnectionInterval time.Duration
 	// ReconnectionAttemptsLimit maximum number of (re)connection attempts or 0 for infinite
 	reconnectionAttemptsLimit uint
-	// ReconnectionAttemptsLimitMinutes maximum time ( in minutes ) trying to (re)connect or 0 for infinite
-	reconnectionAttemptsLimitMinutes uint
-	// firstAttempt stores the time.Time when last connection succeeded. it restarts when a connection succeeds
-	lastConnection time.Time
-	// number of (re)connection attempts. it restarts when a connection succeeds
-	attempts uint
+	// reconnectionTimeLimit maximum time trying to (re)connect or 0 for infinite
+	reconnectionTimeLimit time.Duration
+	// retryPolicy decides what and when to retry. if nil, a ConstantBackoff of reconnectionInterval is used
+	retryPolicy RetryPolicy
+	// operationRetries is how many times a failed operation may be repeated
+	operationRetries uint
+	// metrics receives measures of operations and connection health
+	metrics Metrics
+	// tracer starts the spans of operations and driver commands
+	tracer Tracer
+	// supervisorInterval is the time between background health checks, or 0 to disable them
+	supervisorInterval time.Duration
+}
+
+// Option sets one of the Options parameters. See NewOptions
+type Option func(*Options)
+
+// NewOptions returns a pointer to mongohelper.Options instance, connecting to the given URI.
+// Every parameter not set by the given Option functions keeps its default value:
+// connection timeout of ConnectionTimeoutSecondsDefault, execution timeout of ExecutionTimeoutSecondsDefault,
+// SecondsBetweenAttemptsMin between reconnection attempts, no attempts or time limits, no insistence, a NopLogger
+// and one retry for operations that fail with retryable errors.
+// Call .Validate() to check the result; New() does it too.
+func NewOptions(uri string, opts ...Option) *Options {
+	o := Options{
+		connString:           uri,
+		connTimeout:          time.Duration(ConnectionTimeoutSecondsDefault) * time.Second,
+		execTimeout:          time.Duration(ExecutionTimeoutSecondsDefault) * time.Second,
+		reconnectionInterval: time.Duration(SecondsBetweenAttemptsMin) * time.Second,
+		operationRetries:     1,
+		logger:               NopLogger{},
+		metrics:              NopMetrics{},
+		tracer:               NopTracer{},
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &o
+}
+
+// WithAppName sets the application name, that helps to identify the source application on database logs
+func WithAppName(name string) Option {
+	return func(o *Options) {
+		o.appName = name
+	}
+}
+
+// WithConnectTimeout sets how much time the engine waits for a connection. Minimum is ConnectionTimeoutSecondsMin
+func WithConnectTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.connTimeout = d
+	}
+}
+
+// WithExecTimeout sets how much time the engine waits for an execution. Minimum is ExecutionTimeoutSecondsMin
+func WithExecTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.execTimeout = d
+	}
+}
+
+// WithReconnectInterval sets the time between (re)connection attempts. Minimum is SecondsBetweenAttemptsMin
+func WithReconnectInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.reconnectionInterval = d
+	}
+}
+
+// WithMaxAttempts sets the maximum number of (re)connection attempts, or 0 for infinite
+func WithMaxAttempts(n uint) Option {
+	return func(o *Options) {
+		o.reconnectionAttemptsLimit = n
+	}
+}
+
+// WithMaxReconnectDuration sets the maximum time trying to (re)connect since the outage began, or 0 for infinite
+func WithMaxReconnectDuration(d time.Duration) Option {
+	return func(o *Options) {
+		o.reconnectionTimeLimit = d
+	}
+}
+
+// WithInsist sets if the engine should retry when it can't connect on first attempt
+func WithInsist(insist bool) Option {
+	return func(o *Options) {
+		o.reconnectionInsistOnFail = insist
+	}
+}
+
+// WithLogger sets the Logger that receives the engine log messages. nil means no logging at all, which is the default
+// See SlogLogger, StdLogger and NopLogger
+func WithLogger(logger Logger) Option {
+	return func(o *Options) {
+		if logger == nil {
+			logger = NopLogger{}
+		}
+
+		o.logger = logger
+	}
+}
+
+// WithMetrics sets the Metrics that receives measures of operations and connection health. nil disables them,
+// which is the default. See MetricsRegistry
+func WithMetrics(m Metrics) Option {
+	return func(o *Options) {
+		if m == nil {
+			m = NopMetrics{}
+		}
+
+		o.metrics = m
+	}
+}
+
+// WithTracer sets the Tracer that receives a span for every operation, retry, reconnection and driver command.
+// nil disables tracing, which is the default
+func WithTracer(t Tracer) Option {
+	return func(o *Options) {
+		if t == nil {
+			t = NopTracer{}
+		}
+
+		o.tracer = t
+	}
+}
+
+// WithRetryPolicy sets the policy that classifies retryable errors and spaces (re)connection and operation attempts
+// By default, a ConstantBackoff of the reconnect interval is used
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(o *Options) {
+		o.retryPolicy = p
+	}
+}
+
+// WithOperationRetries sets how many times an operation that failed with a retryable error may be repeated
+// Default is 1. 0 disables retries, even after a reconnection
+func WithOperationRetries(n uint) Option {
+	return func(o *Options) {
+		o.operationRetries = n
+	}
+}
+
+// WithSupervisor enables a background routine that pings database every interval, updating State() and replacing
+// the client after a few failed pings in a row, or at once if it was disconnected
+// The routine follows the reconnection limits and WithInsist, and stops on Disconnect(). 0 disables it, which is the default
+func WithSupervisor(interval time.Duration) Option {
+	return func(o *Options) {
+		o.supervisorInterval = interval
+	}
+}
+
+// Validate checks every parameter, returning an *OptionError for the first one out of bounds
+func (o *Options) Validate() error {
+	if o.connString == "" {
+		return &OptionError{Option: "uri", Value: o.connString, Reason: "cannot be empty"}
+	}
+
+	if min := time.Duration(ConnectionTimeoutSecondsMin) * time.Second; o.connTimeout < min {
+		return &OptionError{Option: "connect timeout", Value: o.connTimeout, Reason: fmt.Sprintf("minimum allowed is %s", min)}
+	}
+
+	if min := time.Duration(ExecutionTimeoutSecondsMin) * time.Second; o.execTimeout < min {
+		return &OptionError{Option: "exec timeout", Value: o.execTimeout, Reason: fmt.Sprintf("minimum allowed is %s", min)}
+	}
+
+	if min := time.Duration(SecondsBetweenAttemptsMin) * time.Second; o.reconnectionInterval < min {
+		return &OptionError{Option: "reconnect interval", Value: o.reconnectionInterval, Reason: fmt.Sprintf("minimum allowed is %s", min)}
+	}
+
+	if o.reconnectionTimeLimit < 0 {
+		return &OptionError{Option: "max reconnect duration", Value: o.reconnectionTimeLimit, Reason: "cannot be negative"}
+	}
+
+	if o.supervisorInterval < 0 {
+		return &OptionError{Option: "supervisor interval", Value: o.supervisorInterval, Reason: "cannot be negative"}
+	}
+
+	return nil
 }
 
 // OptionsNew returns a pointer to mongohelper.Options instance.
@@ -40,50 +213,52 @@ type Options struct {
 // reconnecAttemptsLimit maximum number of (re)connection attempts or 0 for infinite
 // reconnectAttemptsLimitMinutes maximum time ( in minutes ) trying to (re)connect or 0 for infinite
 // insistOnFail If can't connect on first attempt, if should retry
-// logMessages if true allow the engine to print out log messages to stdout
+// logMessages if true allow the engine to print out log messages, through StdLogger
+//
+// Deprecated: values out of bounds are silently replaced by defaults. Use NewOptions() instead.
 func OptionsNew(appName, connectionString string, connectTimeoutInSeconds, execTimeoutInSeconds, reconnectTimeInSeconds, reconnecAttemptsLimit, reconnectAttemptsLimitMinutes uint, insistOnFail, logMessages bool) *Options {
-	logIfAllowed := func(msg string) {
-		if logMessages {
-			log.Println(msg)
-		}
+	var logger Logger = NopLogger{}
+
+	if logMessages {
+		logger = StdLogger(log.Default(), false)
 	}
 
 	if appName == "" {
-		logIfAllowed("empty application name")
+		logger.Warn("empty application name", Field{FieldRoutine, "OptionsNew"})
 	}
 
 	if connectionString == "" {
-		logIfAllowed("empty connection string")
+		logger.Error("empty connection string", Field{FieldRoutine, "OptionsNew"})
 
 		return nil
 	}
 
 	if connectTimeoutInSeconds < ConnectionTimeoutSecondsMin {
-		logIfAllowed(fmt.Sprintf("value too low for connectTimeoutInSeconds: %d, when minimum allowed is %d; using default mongohelper.ConnectionTimeoutSecondsDefault: %d instead\n", connectTimeoutInSeconds, SecondsBetweenAttemptsMin, ConnectionTimeoutSecondsDefault))
+		logger.Warn(fmt.Sprintf("value too low for connectTimeoutInSeconds: %d, when minimum allowed is %d; using default mongohelper.ConnectionTimeoutSecondsDefault: %d instead", connectTimeoutInSeconds, ConnectionTimeoutSecondsMin, ConnectionTimeoutSecondsDefault), Field{FieldRoutine, "OptionsNew"})
 
 		connectTimeoutInSeconds = ConnectionTimeoutSecondsDefault
 	}
 
 	if reconnectTimeInSeconds < SecondsBetweenAttemptsMin {
-		logIfAllowed(fmt.Sprintf("value too low for reconnectTimeInSeconds: %d, when minimum allowed is %d; using default mongohelper.SecondsBetweenAttemptsMin: %d instead\n", reconnectTimeInSeconds, SecondsBetweenAttemptsMin, SecondsBetweenAttemptsMin))
+		logger.Warn(fmt.Sprintf("value too low for reconnectTimeInSeconds: %d, when minimum allowed is %d; using default mongohelper.SecondsBetweenAttemptsMin: %d instead", reconnectTimeInSeconds, SecondsBetweenAttemptsMin, SecondsBetweenAttemptsMin), Field{FieldRoutine, "OptionsNew"})
 
 		reconnectTimeInSeconds = SecondsBetweenAttemptsMin
 	}
 
 	if execTimeoutInSeconds < ExecutionTimeoutSecondsMin {
-		logIfAllowed(fmt.Sprintf("value too low for execTimeoutInSeconds: %d, when minimum allowed is %d; using default mongohelper.ExecutionTimeoutSecondsDefault: %d instead\n", execTimeoutInSeconds, SecondsBetweenAttemptsMin, ExecutionTimeoutSecondsDefault))
+		logger.Warn(fmt.Sprintf("value too low for execTimeoutInSeconds: %d, when minimum allowed is %d; using default mongohelper.ExecutionTimeoutSecondsDefault: %d instead", execTimeoutInSeconds, ExecutionTimeoutSecondsMin, ExecutionTimeoutSecondsDefault), Field{FieldRoutine, "OptionsNew"})
 
 		execTimeoutInSeconds = ExecutionTimeoutSecondsDefault
 	}
 
-	return &Options{
-		connString:                         connectionString,
-		connTimeoutSeconds:                 connectTimeoutInSeconds,
-		execTimeoutSeconds:                 execTimeoutInSeconds,
-		reconnectionInsistOnFail:           insistOnFail,
-		reconnectionSecondsBetweenAttempts: reconnectTimeInSeconds,
-		reconnectionAttemptsLimit:          reconnecAttemptsLimit,
-		reconnectionAttemptsLimitMinutes:   reconnectAttemptsLimitMinutes,
-		printLogMessages:                   logMessages,
-	}
+	return NewOptions(connectionString,
+		WithAppName(appName),
+		WithConnectTimeout(time.Duration(connectTimeoutInSeconds)*time.Second),
+		WithExecTimeout(time.Duration(execTimeoutInSeconds)*time.Second),
+		WithReconnectInterval(time.Duration(reconnectTimeInSeconds)*time.Second),
+		WithMaxAttempts(reconnecAttemptsLimit),
+		WithMaxReconnectDuration(time.Duration(reconnectAttemptsLimitMinutes)*time.Minute),
+		WithInsist(insistOnFail),
+		WithLogger(logger),
+	)
 }
@@ -0,0 +1,77 @@
+package mongohelper
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewOptions(t *testing.T) {
+	o := NewOptions(testConnectionString, WithAppName("mongohelpertest"), WithExecTimeout(20*time.Second), WithMaxAttempts(3))
+
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.appName != "mongohelpertest" || o.execTimeout != 20*time.Second || o.reconnectionAttemptsLimit != 3 {
+		t.Errorf("options not applied: %+v", o)
+	}
+
+	if o.connTimeout != time.Duration(ConnectionTimeoutSecondsDefault)*time.Second {
+		t.Errorf("expected default connect timeout, got %s", o.connTimeout)
+	}
+
+	if o := OptionsNew("mongohelpertest", testConnectionString, 10, 10, 10, 0, 0, false, false); o.appName != "mongohelpertest" {
+		t.Errorf("expected OptionsNew to keep the application name, got %q", o.appName)
+	}
+}
+
+func TestOptionsNew_logger(t *testing.T) {
+	var buf bytes.Buffer
+
+	log.SetOutput(&buf)
+
+	defer log.SetOutput(os.Stderr)
+
+	o := OptionsNew("mongohelpertest", testConnectionString, 0, 10, 10, 0, 0, false, true)
+
+	if o.connTimeout != time.Duration(ConnectionTimeoutSecondsDefault)*time.Second {
+		t.Errorf("expected default connect timeout, got %s", o.connTimeout)
+	}
+
+	if s := buf.String(); !strings.Contains(s, "WARN") || !strings.Contains(s, "connectTimeoutInSeconds") || !strings.Contains(s, "OptionsNew") {
+		t.Errorf("expected the warning through StdLogger, got %q", s)
+	}
+
+	buf.Reset()
+
+	OptionsNew("mongohelpertest", testConnectionString, 0, 10, 10, 0, 0, false, false)
+
+	if buf.Len() > 0 {
+		t.Errorf("expected no messages, got %q", buf.String())
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	cases := map[string]*Options{
+		"empty uri":          NewOptions(""),
+		"connect timeout":    NewOptions(testConnectionString, WithConnectTimeout(time.Second)),
+		"exec timeout":       NewOptions(testConnectionString, WithExecTimeout(time.Millisecond)),
+		"reconnect interval": NewOptions(testConnectionString, WithReconnectInterval(time.Second)),
+		"reconnect duration": NewOptions(testConnectionString, WithMaxReconnectDuration(-time.Minute)),
+	}
+
+	for name, o := range cases {
+		err := o.Validate()
+
+		var oe *OptionError
+
+		if !errors.Is(err, ErrInvalidOption) || !errors.As(err, &oe) {
+			t.Errorf("%s: expected an *OptionError, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package mongohelper
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// DefaultPageSize is the page size used when PageRequest.Size isn't positive
+const DefaultPageSize = 20
+
+// PageRequest describes the page wanted from Paginate()
+type PageRequest struct {
+	// After is the Next token of the previous page. Empty, along with Before, means the first page
+	After string
+	// Before is the Prev token of the following page, for backward paging. It can't be given along with After
+	Before string
+	// Size is the maximum number of documents in the page. DefaultPageSize is used when it's not positive
+	Size int64
+	// SortField orders the pages, with _id breaking ties. It should exist in every document. Defaults to _id
+	SortField string
+	// Descending reverses the order
+	Descending bool
+	// WithTotal counts every document matching the filter, with CountDocs, into Page.Total
+	WithTotal bool
+}
+
+// Page describes the page returned by Paginate()
+type Page struct {
+	// Next is the token for the following page, as PageRequest.After. It's empty on the last page
+	Next string
+	// Prev is the token for the preceding page, as PageRequest.Before. It's empty on the first page
+	Prev string
+	// Total is the number of documents matching the filter, if PageRequest.WithTotal was set
+	Total int64
+}
+
+// pageToken is the content of the continuation tokens: the sort field and order, and the sort key and _id of the
+// document the page starts after, or ends before
+type pageToken struct {
+	Field      string        `bson:"f"`
+	Descending bool          `bson:"d"`
+	Key        bson.RawValue `bson:"k"`
+	ID         bson.RawValue `bson:"i"`
+}
+
+// encode returns the token as an URL-safe string
+func (t pageToken) encode() (string, error) {
+	b, err := bson.Marshal(t)
+
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodePageToken parses s, checking it was issued for the same sort field and order
+func decodePageToken(s string, field string, descending bool) (pageToken, error) {
+	var t pageToken
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return t, fmt.Errorf("malformed token: %w", err)
+	}
+
+	if err := bson.Unmarshal(b, &t); err != nil {
+		return t, fmt.Errorf("malformed token: %w", err)
+	}
+
+	if t.Field != field || t.Descending != descending {
+		return t, fmt.Errorf("the token was issued for another sort order")
+	}
+
+	return t, nil
+}
+
+// Paginate returns, into dest, a page of the documents matching filter, using keyset pagination: instead of skipping
+// documents, each page starts after the sort key and _id of the previous page's last document, encoded in an opaque
+// and URL-safe token. So it stays fast on big collections, as long as an index covers the sort field and _id
+// dest must be a pointer to a slice. The documents are always returned in the requested order, even when paging backwards
+func (l *Link) Paginate(database, collection string, filter interface{}, req PageRequest, dest interface{}) (*Page, error) {
+	return l.PaginateCtx(context.Background(), database, collection, filter, req, dest)
+}
+
+// PaginateCtx works like Paginate, but honors the cancellation and deadline of the given context
+// The configured execution timeout applies to each query, the page and the eventual count
+func (l *Link) PaginateCtx(ctx context.Context, database, collection string, filter interface{}, req PageRequest, dest interface{}) (*Page, error) {
+	return paginate(ctx, l, database, collection, filter, req, dest)
+}
+
+// paginate implements Paginate over any Store, through FindCtx and CountDocsCtx
+func paginate(ctx context.Context, s Store, database, collection string, filter interface{}, req PageRequest, dest interface{}) (*Page, error) {
+	invalid := func(err error) error {
+		return &OpError{Op: "Paginate", Database: database, Collection: collection, Kind: ErrInvalidPageRequest, Err: err}
+	}
+
+	if dest == nil {
+		return nil, &OpError{Op: "Paginate", Database: database, Collection: collection, Kind: ErrNilDestination}
+	}
+
+	rv := reflect.ValueOf(dest)
+
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return nil, invalid(fmt.Errorf("dest must be a pointer to a slice, got %T", dest))
+	}
+
+	if req.After != "" && req.Before != "" {
+		return nil, invalid(fmt.Errorf("After and Before can't be given together"))
+	}
+
+	if req.Size <= 0 {
+		req.Size = DefaultPageSize
+	}
+
+	if req.SortField == "" {
+		req.SortField = "_id"
+	}
+
+	backward := req.Before != ""
+
+	// paging backwards is paging forwards in the reverse order, then reversing the page
+	descending := req.Descending != backward
+
+	conditions := []interface{}{}
+
+	if filter != nil {
+		conditions = append(conditions, filter)
+	}
+
+	if token := req.After + req.Before; token != "" {
+		t, err := decodePageToken(token, req.SortField, req.Descending)
+
+		if err != nil {
+			return nil, invalid(err)
+		}
+
+		conditions = append(conditions, keysetCondition(req.SortField, descending, t))
+	}
+
+	var q interface{} = bson.D{}
+
+	switch len(conditions) {
+	case 1:
+		q = conditions[0]
+	case 2:
+		q = bson.D{{Key: "$and", Value: bson.A(conditions)}}
+	}
+
+	order := []string{req.SortField, "_id"}
+
+	if req.SortField == "_id" {
+		order = order[:1]
+	}
+
+	if descending {
+		for i := range order {
+			order[i] = "-" + order[i]
+		}
+	}
+
+	var docs []bson.Raw
+
+	if err := s.FindCtx(ctx, database, collection, q, &docs, Sort(order...), Limit(req.Size+1)); err != nil {
+		return nil, err
+	}
+
+	more := int64(len(docs)) > req.Size
+
+	if more {
+		docs = docs[:req.Size]
+	}
+
+	if backward {
+		for i, j := 0, len(docs)-1; i < j; i, j = i+1, j-1 {
+			docs[i], docs[j] = docs[j], docs[i]
+		}
+	}
+
+	page := &Page{}
+
+	if len(docs) > 0 {
+		var err error
+
+		// forwards, there's a next page when the query found more documents; backwards, there's always the page
+		// the token came from. The same goes for the previous page, the other way around
+		if more || backward {
+			if page.Next, err = newPageToken(docs[len(docs)-1], req).encode(); err != nil {
+				return nil, invalid(err)
+			}
+		}
+
+		if (backward && more) || req.After != "" {
+			if page.Prev, err = newPageToken(docs[0], req).encode(); err != nil {
+				return nil, invalid(err)
+			}
+		}
+	}
+
+	slice := reflect.MakeSlice(rv.Elem().Type(), len(docs), len(docs))
+
+	for i, doc := range docs {
+		if err := bson.Unmarshal(doc, slice.Index(i).Addr().Interface()); err != nil {
+			return nil, &OpError{Op: "Paginate", Database: database, Collection: collection, Err: err}
+		}
+	}
+
+	rv.Elem().Set(slice)
+
+	if req.WithTotal {
+		if filter == nil {
+			filter = bson.D{}
+		}
+
+		n, err := s.CountDocsCtx(ctx, database, collection, filter)
+
+		if err != nil {
+			return nil, err
+		}
+
+		page.Total = n
+	}
+
+	return page, nil
+}
+
+// newPageToken returns the token pointing at doc
+func newPageToken(doc bson.Raw, req PageRequest) pageToken {
+	return pageToken{
+		Field:      req.SortField,
+		Descending: req.Descending,
+		Key:        rawOrNull(doc.Lookup(strings.Split(req.SortField, ".")...)),
+		ID:         rawOrNull(doc.Lookup("_id")),
+	}
+}
+
+// rawOrNull returns v, or a null value when v is missing
+func rawOrNull(v bson.RawValue) bson.RawValue {
+	if v.Type == 0 {
+		return bson.RawValue{Type: bsontype.Null}
+	}
+
+	return v
+}
+
+// keysetCondition selects the documents that come after the token position, in the given order
+func keysetCondition(field string, descending bool, t pageToken) bson.D {
+	op := "$gt"
+
+	if descending {
+		op = "$lt"
+	}
+
+	if field == "_id" {
+		return bson.D{{Key: "_id", Value: bson.D{{Key: op, Value: t.ID}}}}
+	}
+
+	return bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: field, Value: bson.D{{Key: op, Value: t.Key}}}},
+		bson.D{{Key: field, Value: t.Key}, {Key: "_id", Value: bson.D{{Key: op, Value: t.ID}}}},
+	}}}
+}
@@ -0,0 +1,90 @@
+package mongohelper
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPaginate(t *testing.T) {
+	m := MemoryStoreNew()
+
+	// scores repeat, so _id must break the ties
+	for i := 1; i <= 7; i++ {
+		if _, err := m.InsertOne(testDB, testCollection, bson.M{"_id": i, "score": i / 2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(docs []bson.M) []int32 {
+		var a []int32
+
+		for _, d := range docs {
+			a = append(a, d["_id"].(int32))
+		}
+
+		return a
+	}
+
+	req := PageRequest{Size: 3, SortField: "score", Descending: true, WithTotal: true}
+
+	var pages [][]int32
+
+	page := &Page{}
+
+	for first := true; first || page.Next != ""; first = false {
+		var docs []bson.M
+
+		req.After = page.Next
+
+		var err error
+
+		if page, err = m.Paginate(testDB, testCollection, nil, req, &docs); err != nil {
+			t.Fatal(err)
+		}
+
+		if page.Total != 7 {
+			t.Errorf("expected a total of 7, got %d", page.Total)
+		}
+
+		if first && page.Prev != "" {
+			t.Error("expected no previous page on the first page")
+		}
+
+		pages = append(pages, ids(docs))
+	}
+
+	want := [][]int32{{7, 6, 5}, {4, 3, 2}, {1}}
+
+	if len(pages) != len(want) {
+		t.Fatalf("expected pages %v, got %v", want, pages)
+	}
+
+	for i := range want {
+		if len(pages[i]) != len(want[i]) || pages[i][0] != want[i][0] || pages[i][len(pages[i])-1] != want[i][len(want[i])-1] {
+			t.Errorf("expected pages %v, got %v", want, pages)
+		}
+	}
+
+	// back from the last page
+	var docs []bson.M
+
+	back, err := m.Paginate(testDB, testCollection, nil, PageRequest{Before: page.Prev, Size: 3, SortField: "score", Descending: true}, &docs)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ids(docs); len(got) != 3 || got[0] != 4 || got[2] != 2 || back.Prev == "" || back.Next == "" {
+		t.Errorf("expected the middle page [4 3 2] with both tokens, got %v %+v", got, back)
+	}
+
+	if _, err := m.Paginate(testDB, testCollection, nil, PageRequest{After: page.Prev}, &docs); !errors.Is(err, ErrInvalidPageRequest) {
+		t.Errorf("expected a token issued for another order to be rejected, got %v", err)
+	}
+
+	if _, err := m.Paginate(testDB, testCollection, nil, PageRequest{After: "%%%"}, &docs); !errors.Is(err, ErrInvalidPageRequest) {
+		t.Errorf("expected a malformed token to be rejected, got %v", err)
+	}
+}
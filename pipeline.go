@@ -0,0 +1,177 @@
+package mongohelper
+
+import (
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Pipeline is an aggregation pipeline built fluently, like
+// PipelineNew().Match(Where("status").Eq("a")).Group("$city", AccSum("total", "$amount")).Sort("-total")
+// It's a slice of stages, so it's accepted anywhere a pipeline is, including Link.Aggregate(), $facet and $lookup
+// Pipelines are immutable: every method returns a new one
+type Pipeline []bson.D
+
+// PipelineNew returns an empty Pipeline, to be filled by its methods
+func PipelineNew() Pipeline {
+	return Pipeline{}
+}
+
+// Stage appends any stage, for the ones without a method of their own
+func (p Pipeline) Stage(name string, value interface{}) Pipeline {
+	return append(p[:len(p):len(p)], bson.D{{Key: name, Value: value}})
+}
+
+// Match keeps the documents matching filter, a Filter or any filter document
+func (p Pipeline) Match(filter interface{}) Pipeline {
+	if filter == nil {
+		filter = bson.D{}
+	}
+
+	return p.Stage("$match", filter)
+}
+
+// Group groups documents by the id expression, like "$city" or nil for a single group, computing the accumulators
+func (p Pipeline) Group(id interface{}, accumulators ...Accumulator) Pipeline {
+	d := bson.D{{Key: "_id", Value: id}}
+
+	for _, a := range accumulators {
+		d = append(d, bson.E{Key: a.Field, Value: bson.D{{Key: a.Operator, Value: a.Expression}}})
+	}
+
+	return p.Stage("$group", d)
+}
+
+// Project reshapes the documents, like bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 0}}
+func (p Pipeline) Project(projection interface{}) Pipeline {
+	return p.Stage("$project", projection)
+}
+
+// Sort orders the documents by the given fields. A field prefixed by "-" sorts in descending order
+func (p Pipeline) Sort(fields ...string) Pipeline {
+	return p.Stage("$sort", sortDoc(fields))
+}
+
+// Limit keeps the first n documents
+func (p Pipeline) Limit(n int64) Pipeline {
+	return p.Stage("$limit", n)
+}
+
+// Skip drops the first n documents
+func (p Pipeline) Skip(n int64) Pipeline {
+	return p.Stage("$skip", n)
+}
+
+// Lookup joins the documents of another collection whose foreignField equals localField, into the array field as
+func (p Pipeline) Lookup(from, localField, foreignField, as string) Pipeline {
+	return p.Stage("$lookup", bson.D{{Key: "from", Value: from}, {Key: "localField", Value: localField}, {Key: "foreignField", Value: foreignField}, {Key: "as", Value: as}})
+}
+
+// Unwind outputs one document per element of the array at path
+// When preserve is true, documents whose array is missing, null or empty are kept
+func (p Pipeline) Unwind(path string, preserve bool) Pipeline {
+	if !strings.HasPrefix(path, "$") {
+		path = "$" + path
+	}
+
+	if !preserve {
+		return p.Stage("$unwind", path)
+	}
+
+	return p.Stage("$unwind", bson.D{{Key: "path", Value: path}, {Key: "preserveNullAndEmptyArrays", Value: true}})
+}
+
+// Facet runs each sub pipeline over the same input, outputting a single document with one array field per facet
+func (p Pipeline) Facet(facets map[string]Pipeline) Pipeline {
+	names := make([]string, 0, len(facets))
+
+	for name := range facets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	d := bson.D{}
+
+	for _, name := range names {
+		d = append(d, bson.E{Key: name, Value: facets[name]})
+	}
+
+	return p.Stage("$facet", d)
+}
+
+// AddFields adds or overwrites fields, like bson.D{{Key: "total", Value: bson.D{{Key: "$sum", Value: "$items.price"}}}}
+func (p Pipeline) AddFields(fields interface{}) Pipeline {
+	return p.Stage("$addFields", fields)
+}
+
+// Count outputs a single document with the number of documents in the given field
+func (p Pipeline) Count(field string) Pipeline {
+	return p.Stage("$count", field)
+}
+
+// Accumulator computes a field of each $group output document. The Acc functions build them, like AccSum
+type Accumulator struct {
+	// Field is the output field
+	Field string
+	// Operator is the accumulator operator, like "$sum"
+	Operator string
+	// Expression is the operator argument, like "$amount" or 1
+	Expression interface{}
+}
+
+// AccSum sums the expression values. AccSum(field, 1) counts the documents of each group
+func AccSum(field string, expression interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$sum", Expression: expression}
+}
+
+// AccAvg averages the numeric expression values
+func AccAvg(field string, expression interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$avg", Expression: expression}
+}
+
+// AccMin keeps the lowest expression value
+func AccMin(field string, expression interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$min", Expression: expression}
+}
+
+// AccMax keeps the greatest expression value
+func AccMax(field string, expression interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$max", Expression: expression}
+}
+
+// AccFirst keeps the expression value of the first document of each group
+func AccFirst(field string, expression interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$first", Expression: expression}
+}
+
+// AccLast keeps the expression value of the last document of each group
+func AccLast(field string, expression interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$last", Expression: expression}
+}
+
+// AccPush collects the expression values in an array
+func AccPush(field string, expression interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$push", Expression: expression}
+}
+
+// AccAddToSet collects the distinct expression values in an array
+func AccAddToSet(field string, expression interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$addToSet", Expression: expression}
+}
+
+// sortDoc turns field names, prefixed by "-" for descending order, into a sort document
+func sortDoc(fields []string) bson.D {
+	d := bson.D{}
+
+	for _, f := range fields {
+		if strings.HasPrefix(f, "-") {
+			d = append(d, bson.E{Key: f[1:], Value: -1})
+		} else {
+			d = append(d, bson.E{Key: strings.TrimPrefix(f, "+"), Value: 1})
+		}
+	}
+
+	return d
+}
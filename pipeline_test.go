@@ -0,0 +1,89 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPipeline(t *testing.T) {
+	base := PipelineNew().Match(Where("status").Eq("a"))
+
+	p := base.
+		Unwind("items", false).
+		Group("$city", AccSum("total", "$items.price"), AccSum("orders", 1)).
+		Sort("-total", "_id").
+		Skip(5).
+		Limit(10)
+
+	want := Pipeline{
+		{{Key: "$match", Value: Where("status").Eq("a")}},
+		{{Key: "$unwind", Value: "$items"}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$city"}, {Key: "total", Value: bson.D{{Key: "$sum", Value: "$items.price"}}}, {Key: "orders", Value: bson.D{{Key: "$sum", Value: 1}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "total", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$skip", Value: int64(5)}},
+		{{Key: "$limit", Value: int64(10)}},
+	}
+
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("expected %v, got %v", want, p)
+	}
+
+	// builders are immutable
+	base.Count("n")
+
+	if len(base) != 1 {
+		t.Errorf("expected the base pipeline to be untouched, got %v", base)
+	}
+
+	// pipelines nest in $facet and encode as arrays of stages
+	facet := PipelineNew().Facet(map[string]Pipeline{"top": PipelineNew().Limit(3), "total": PipelineNew().Count("n")})
+
+	d, err := toDoc(bson.D{{Key: "p", Value: facet}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stages := d[0].Value.(bson.A)
+
+	if facets := stages[0].(bson.D)[0].Value.(bson.D); len(facets) != 2 || facets[0].Key != "top" || len(facets[0].Value.(bson.A)) != 1 {
+		t.Errorf("unexpected $facet stage %v", stages[0])
+	}
+}
+
+func TestLink_Aggregate(t *testing.T) {
+	var (
+		l    Link
+		seen *Operation
+	)
+
+	l.Use(func(next OpFunc) OpFunc {
+		return func(ctx context.Context, op *Operation) (*OpResult, error) {
+			seen = op
+
+			return next(ctx, op)
+		}
+	})
+
+	var dest []bson.M
+
+	if err := l.Aggregate(testDB, testCollection, PipelineNew().Count("n"), nil); !errors.Is(err, ErrNilDestination) {
+		t.Errorf("expected a nil destination error, got %v", err)
+	}
+
+	if err := l.Aggregate(testDB, testCollection, PipelineNew().Count("n"), &dest); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("expected the operation to reach the uninitialized link, got %v", err)
+	}
+
+	if seen == nil || seen.Kind != OpAggregate || seen.Pipeline == nil || seen.Dest != &dest {
+		t.Fatalf("expected the aggregation to go through the interceptors, got %+v", seen)
+	}
+
+	if s := seen.statement(); s != `{"pipeline":[{"$count":"?"}]}` {
+		t.Errorf("unexpected statement %s", s)
+	}
+}
@@ -0,0 +1,131 @@
+package mongohelper
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RecordedSpan is a span kept by a RecordingTracer
+type RecordedSpan struct {
+	// ID identifies the span, counting from 1 in start order
+	ID int
+	// ParentID is the ID of the parent span, or 0 for root spans
+	ParentID   int
+	Name       string
+	Attributes []Field
+	Events     []SpanEvent
+	Errors     []error
+	Start      time.Time
+	// End is zero until the span ends
+	End time.Time
+}
+
+// Attribute returns the last value set for key
+func (s RecordedSpan) Attribute(key string) (interface{}, bool) {
+	for i := len(s.Attributes) - 1; i >= 0; i-- {
+		if s.Attributes[i].Key == key {
+			return s.Attributes[i].Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// SpanEvent is an event added to a span
+type SpanEvent struct {
+	Name       string
+	Attributes []Field
+	Time       time.Time
+}
+
+// RecordingTracer is a Tracer that keeps every span in memory, for tests and debugging
+type RecordingTracer struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordingTracerNew returns an empty RecordingTracer
+func RecordingTracerNew() *RecordingTracer {
+	return &RecordingTracer{}
+}
+
+// recordingSpanKey holds the current recording span in contexts
+type recordingSpanKey struct{}
+
+// Start begins a span, child of the recording span held by ctx if any
+func (t *RecordingTracer) Start(ctx context.Context, name string, attrs ...Field) (context.Context, Span) {
+	t.mu.Lock()
+
+	defer t.mu.Unlock()
+
+	s := &RecordedSpan{ID: len(t.spans) + 1, Name: name, Attributes: append([]Field(nil), attrs...), Start: time.Now()}
+
+	if parent, ok := ctx.Value(recordingSpanKey{}).(*recordingSpan); ok {
+		s.ParentID = parent.s.ID
+	}
+
+	t.spans = append(t.spans, s)
+
+	span := &recordingSpan{t: t, s: s}
+
+	return context.WithValue(ctx, recordingSpanKey{}, span), span
+}
+
+// Spans returns a copy of every span, in start order
+func (t *RecordingTracer) Spans() []RecordedSpan {
+	t.mu.Lock()
+
+	defer t.mu.Unlock()
+
+	a := make([]RecordedSpan, len(t.spans))
+
+	for i, s := range t.spans {
+		a[i] = *s
+		a[i].Attributes = append([]Field(nil), s.Attributes...)
+		a[i].Events = append([]SpanEvent(nil), s.Events...)
+		a[i].Errors = append([]error(nil), s.Errors...)
+	}
+
+	return a
+}
+
+// recordingSpan writes to its RecordedSpan under the tracer lock
+type recordingSpan struct {
+	t *RecordingTracer
+	s *RecordedSpan
+}
+
+func (r *recordingSpan) SetAttributes(attrs ...Field) {
+	r.t.mu.Lock()
+
+	defer r.t.mu.Unlock()
+
+	r.s.Attributes = append(r.s.Attributes, attrs...)
+}
+
+func (r *recordingSpan) AddEvent(name string, attrs ...Field) {
+	r.t.mu.Lock()
+
+	defer r.t.mu.Unlock()
+
+	r.s.Events = append(r.s.Events, SpanEvent{Name: name, Attributes: append([]Field(nil), attrs...), Time: time.Now()})
+}
+
+func (r *recordingSpan) RecordError(err error) {
+	r.t.mu.Lock()
+
+	defer r.t.mu.Unlock()
+
+	r.s.Errors = append(r.s.Errors, err)
+}
+
+func (r *recordingSpan) End() {
+	r.t.mu.Lock()
+
+	defer r.t.mu.Unlock()
+
+	if r.s.End.IsZero() {
+		r.s.End = time.Now()
+	}
+}
@@ -0,0 +1,41 @@
+package mongohelper
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReplaceOne wraps the mongo.Database.Collection.ReplaceOne() method
+// It returns the number of matched records and an error
+// The filter parameter must be a document containing query operators and can be used to select the document to be
+// replaced. It cannot be nil. If the filter does not match any documents, the operation will succeed and an
+// UpdateResult with a MatchedCount of 0 will be returned.
+//
+// The replacement parameter must be a document that will be used to replace the selected document. It cannot be nil
+// and cannot contain any update operators (https://docs.mongodb.com/manual/reference/operator/update/).
+func (l *Link) ReplaceOne(database, collection string, filter, replacement interface{}) (int64, error) {
+	return l.ReplaceOneCtx(context.Background(), database, collection, filter, replacement)
+}
+
+// ReplaceOneCtx works like ReplaceOne, but honors the cancellation and deadline of the given context
+// The configured execution timeout still applies as an upper bound, including the eventual reconnection
+func (l *Link) ReplaceOneCtx(ctx context.Context, database, collection string, filter, replacement interface{}) (int64, error) {
+	op := Operation{Kind: OpReplaceOne, Database: database, Collection: collection, Filter: filter, Update: replacement}
+
+	rs, err := l.run(ctx, &op, func(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error) {
+		rs, err := client.Database(op.Database).Collection(op.Collection).ReplaceOne(ctx, op.Filter, op.Update, options.Replace())
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &OpResult{MatchedCount: rs.MatchedCount, ModifiedCount: rs.ModifiedCount}, nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return rs.MatchedCount, nil
+}
@@ -0,0 +1,204 @@
+package mongohelper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Repository is a typed view of one collection, bound to a Store, usually a *Link
+// T must be a struct; the field tagged as bson:"_id" is used by FindByID, Replace and Delete
+type Repository[T any] struct {
+	link       Store
+	database   string
+	collection string
+	// idIndex is the index path of the field tagged as bson:"_id", or nil if T has no such field
+	idIndex []int
+}
+
+// RepositoryNew returns a Repository for the given database and collection, using the given link
+// A MemoryStore may be given instead of a *Link, for testing purposes
+func RepositoryNew[T any](link Store, database, collection string) *Repository[T] {
+	return &Repository[T]{
+		link:       link,
+		database:   database,
+		collection: collection,
+		idIndex:    idFieldIndex(reflect.TypeOf((*T)(nil)).Elem()),
+	}
+}
+
+// Find returns all documents matching the given filter. A nil filter matches every document
+// The opts parameter sorts, limits, skips and projects the results, like Sort("-age"), Limit(10)
+func (r *Repository[T]) Find(filter interface{}, opts ...FindOption) ([]T, error) {
+	var a []T
+
+	if err := r.link.Find(r.database, r.collection, filter, &a, opts...); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// FindOne returns the first document matching the given filter, in the order given by opts
+func (r *Repository[T]) FindOne(filter interface{}, opts ...FindOption) (T, error) {
+	var x T
+
+	err := r.link.FindOne(r.database, r.collection, filter, &x, opts...)
+
+	return x, err
+}
+
+// FindByID returns the document whose _id equals the given id
+// If T's _id field is an ObjectID, a hexadecimal string id is converted before searching
+func (r *Repository[T]) FindByID(id interface{}) (T, error) {
+	var x T
+
+	id, err := r.normalizeID(id)
+
+	if err != nil {
+		return x, err
+	}
+
+	return r.FindOne(bson.M{"_id": id})
+}
+
+// Insert stores the given document
+// If T's _id field is an empty ObjectID, a new one is generated
+// It returns the ObjectID hexadecimal representation, or an empty string for other _id types
+func (r *Repository[T]) Insert(doc T) (string, error) {
+	return r.link.InsertOne(r.database, r.collection, r.withID(doc))
+}
+
+// InsertMany stores all the given documents, generating ObjectIDs as Insert does
+// It returns the ObjectIDs hexadecimal representations
+func (r *Repository[T]) InsertMany(docs []T) ([]string, error) {
+	a := make([]interface{}, len(docs))
+
+	for i, doc := range docs {
+		a[i] = r.withID(doc)
+	}
+
+	return r.link.InsertMany(r.database, r.collection, a)
+}
+
+// Replace overwrites the stored document that has the same _id as the given one
+// It returns the number of matched documents
+func (r *Repository[T]) Replace(doc T) (int64, error) {
+	id, err := r.id(doc)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return r.link.ReplaceOne(r.database, r.collection, bson.M{"_id": id}, doc)
+}
+
+// Delete removes the stored document that has the same _id as the given one
+// It returns the number of deleted documents
+func (r *Repository[T]) Delete(doc T) (int64, error) {
+	id, err := r.id(doc)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return r.link.DeleteOne(r.database, r.collection, bson.M{"_id": id})
+}
+
+// Count returns the number of documents matching the given filter. A nil filter counts every document
+func (r *Repository[T]) Count(filter interface{}, opts ...FindOption) (int64, error) {
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	return r.link.CountDocs(r.database, r.collection, filter, opts...)
+}
+
+// id returns the value of doc's _id field
+func (r *Repository[T]) id(doc T) (interface{}, error) {
+	if r.idIndex == nil {
+		return nil, fmt.Errorf(`type %T has no field tagged as bson:"_id"`, doc)
+	}
+
+	v := reflect.ValueOf(doc)
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, fmt.Errorf("given document is null")
+		}
+
+		v = v.Elem()
+	}
+
+	return v.FieldByIndex(r.idIndex).Interface(), nil
+}
+
+// withID returns doc, generating a new ObjectID first when its _id field is an empty ObjectID
+func (r *Repository[T]) withID(doc T) T {
+	if r.idIndex == nil {
+		return doc
+	}
+
+	v := reflect.ValueOf(&doc).Elem()
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return doc
+		}
+
+		v = v.Elem()
+	}
+
+	f := v.FieldByIndex(r.idIndex)
+
+	if oid, ok := f.Interface().(primitive.ObjectID); ok && oid.IsZero() && f.CanSet() {
+		f.Set(reflect.ValueOf(primitive.NewObjectID()))
+	}
+
+	return doc
+}
+
+// normalizeID converts hexadecimal strings to ObjectID when T's _id field is an ObjectID
+func (r *Repository[T]) normalizeID(id interface{}) (interface{}, error) {
+	s, ok := id.(string)
+
+	if !ok || r.idIndex == nil {
+		return id, nil
+	}
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.FieldByIndex(r.idIndex).Type != reflect.TypeOf(primitive.ObjectID{}) {
+		return id, nil
+	}
+
+	return primitive.ObjectIDFromHex(s)
+}
+
+// idFieldIndex looks for the struct field tagged as bson:"_id" in t, or in the struct t points to
+func idFieldIndex(t reflect.Type) []int {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if name := strings.Split(f.Tag.Get("bson"), ",")[0]; name == "_id" {
+			return f.Index
+		}
+	}
+
+	return nil
+}
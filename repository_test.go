@@ -0,0 +1,41 @@
+package mongohelper
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRepository_idHandling(t *testing.T) {
+	r := RepositoryNew[testDocStruct](nil, testDB, testCollection)
+
+	if r.idIndex == nil {
+		t.Fatal(`expected the field tagged as bson:"_id" to be found`)
+	}
+
+	doc := r.withID(testDocStruct{Name: "no id yet"})
+
+	if doc.ID.IsZero() {
+		t.Error("expected an ObjectID to be generated")
+	}
+
+	if id, err := r.id(doc); err != nil || id != doc.ID {
+		t.Errorf("expected id %s, got %v ( %v )", doc.ID.Hex(), id, err)
+	}
+
+	if id, err := r.normalizeID(doc.ID.Hex()); err != nil || id != doc.ID {
+		t.Errorf("expected hexadecimal id to be converted to %s, got %v ( %v )", doc.ID.Hex(), id, err)
+	}
+
+	if _, err := r.normalizeID("not an hex"); err == nil {
+		t.Error("expected an error converting an invalid hexadecimal id")
+	}
+
+	type noID struct {
+		ID primitive.ObjectID
+	}
+
+	if _, err := RepositoryNew[noID](nil, testDB, testCollection).id(noID{}); err == nil {
+		t.Error(`expected an error for a type without bson:"_id" field`)
+	}
+}
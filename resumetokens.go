@@ -0,0 +1,107 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ResumeTokenStore persists the resume tokens of change stream subscriptions, so a Watcher continues where it
+// stopped, even after a restart. name identifies the subscription
+type ResumeTokenStore interface {
+	// Load returns the last token saved for name, or nil if there's none
+	Load(ctx context.Context, name string) (bson.Raw, error)
+	// Save keeps token as the last one of name
+	Save(ctx context.Context, name string, token bson.Raw) error
+}
+
+// MemoryTokenStore is a ResumeTokenStore that lives in memory, so it only survives reconnections, not restarts
+// It's safe for concurrent use
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]bson.Raw
+}
+
+// MemoryTokenStoreNew returns an empty MemoryTokenStore
+func MemoryTokenStoreNew() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: make(map[string]bson.Raw)}
+}
+
+// Load returns the last token saved for name, or nil
+func (m *MemoryTokenStore) Load(_ context.Context, name string) (bson.Raw, error) {
+	m.mu.RLock()
+
+	defer m.mu.RUnlock()
+
+	return m.tokens[name], nil
+}
+
+// Save keeps a copy of token as the last one of name
+func (m *MemoryTokenStore) Save(_ context.Context, name string, token bson.Raw) error {
+	m.mu.Lock()
+
+	defer m.mu.Unlock()
+
+	m.tokens[name] = append(bson.Raw(nil), token...)
+
+	return nil
+}
+
+// CollectionTokenStore is a ResumeTokenStore that keeps one document per subscription in a collection,
+// like { _id: name, token: {...}, updatedAt: date }
+type CollectionTokenStore struct {
+	store      Store
+	database   string
+	collection string
+}
+
+// CollectionTokenStoreNew returns a CollectionTokenStore that uses the given database and collection of store,
+// usually a *Link
+func CollectionTokenStoreNew(store Store, database, collection string) *CollectionTokenStore {
+	return &CollectionTokenStore{store: store, database: database, collection: collection}
+}
+
+// tokenDoc is the stored document of a subscription
+type tokenDoc struct {
+	Name      string    `bson:"_id"`
+	Token     bson.Raw  `bson:"token"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+// Load returns the last token saved for name, or nil
+func (c *CollectionTokenStore) Load(ctx context.Context, name string) (bson.Raw, error) {
+	var doc tokenDoc
+
+	if err := c.store.FindOneCtx(ctx, c.database, c.collection, bson.M{"_id": name}, &doc); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return doc.Token, nil
+}
+
+// Save stores token as the last one of name, creating the subscription document when needed
+func (c *CollectionTokenStore) Save(ctx context.Context, name string, token bson.Raw) error {
+	update := bson.M{"$set": bson.M{"token": token, "updatedAt": time.Now().UTC()}}
+
+	n, err := c.store.UpdateOneCtx(ctx, c.database, c.collection, bson.M{"_id": name}, update)
+
+	if err != nil || n > 0 {
+		return err
+	}
+
+	_, err = c.store.InsertOneCtx(ctx, c.database, c.collection, tokenDoc{Name: name, Token: token, UpdatedAt: time.Now().UTC()})
+
+	// another instance created it meanwhile
+	if errors.Is(err, ErrDuplicateKey) {
+		_, err = c.store.UpdateOneCtx(ctx, c.database, c.collection, bson.M{"_id": name}, update)
+	}
+
+	return err
+}
@@ -0,0 +1,206 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+// RetryPolicy decides which failures deserve a new attempt, and how long to wait before it
+// It's used both by the (re)connection loop and by the operation wrappers
+type RetryPolicy interface {
+	// Backoff returns the time to wait before the given attempt, counting from 1
+	Backoff(attempt uint) time.Duration
+	// Retryable reports if an operation that failed with err may be attempted again
+	Retryable(err error) bool
+}
+
+// retryableLabels are the error labels that mark an error as transient
+var retryableLabels = []string{"NetworkError", "RetryableWriteError", "TransientTransactionError"}
+
+// retryableCodes are server error codes related to failover, shutdown and network issues
+var retryableCodes = map[int]bool{
+	6:     true, // HostUnreachable
+	7:     true, // HostNotFound
+	89:    true, // NetworkTimeout
+	91:    true, // ShutdownInProgress
+	189:   true, // PrimarySteppedDown
+	262:   true, // ExceededTimeLimit
+	9001:  true, // SocketException
+	10107: true, // NotWritablePrimary, formerly NotMaster
+	11600: true, // InterruptedAtShutdown
+	11602: true, // InterruptedDueToReplStateChange
+	13435: true, // NotPrimaryNoSecondaryOk, formerly NotMasterNoSlaveOk
+	13436: true, // NotPrimaryOrSecondary, formerly NotMasterOrSecondary
+}
+
+// IsRetryable is the default error classification used by the RetryPolicy implementations of this package
+// It returns true for disconnected clients, network and server selection errors, errors labeled as
+// NetworkError, RetryableWriteError or TransientTransactionError, and primary stepdown or shutdown codes
+// Context cancellation and deadline errors are never retryable
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if notSent(err) {
+		return true
+	}
+
+	var ce mongo.CommandError
+
+	if errors.As(err, &ce) {
+		for _, label := range retryableLabels {
+			if ce.HasErrorLabel(label) {
+				return true
+			}
+		}
+
+		return retryableCodes[int(ce.Code)]
+	}
+
+	var we mongo.WriteException
+
+	if errors.As(err, &we) {
+		return we.WriteConcernError != nil && retryableCodes[we.WriteConcernError.Code]
+	}
+
+	var conn topology.ConnectionError
+
+	if errors.As(err, &conn) {
+		return true
+	}
+
+	var ne net.Error
+
+	return errors.As(err, &ne)
+}
+
+// notSent reports if err proves the command never reached the server: the client was disconnected, or no server
+// could be selected
+func notSent(err error) bool {
+	return errors.Is(err, mongo.ErrClientDisconnected) || isServerSelectionError(err)
+}
+
+// isServerSelectionError reports if err means no server was available. The driver doesn't wrap a sentinel for it,
+// so it's known by its message
+func isServerSelectionError(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if strings.HasPrefix(err.Error(), "server selection error") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// classify applies the given classification function, or IsRetryable if it's nil
+func classify(fn func(error) bool, err error) bool {
+	if fn == nil {
+		return IsRetryable(err)
+	}
+
+	return fn(err)
+}
+
+// ConstantBackoff waits the same Interval before every attempt
+type ConstantBackoff struct {
+	Interval time.Duration
+	// Classify overrides IsRetryable, if not nil
+	Classify func(error) bool
+}
+
+// Backoff returns b.Interval
+func (b ConstantBackoff) Backoff(uint) time.Duration {
+	return b.Interval
+}
+
+// Retryable reports if err may be retried
+func (b ConstantBackoff) Retryable(err error) bool {
+	return classify(b.Classify, err)
+}
+
+// ExponentialBackoff waits Initial before the first attempt, multiplying the wait by Multiplier, up to Max
+type ExponentialBackoff struct {
+	Initial time.Duration
+	// Max limits the wait; 0 means no limit
+	Max time.Duration
+	// Multiplier is the growth factor between attempts; values lower than 1 mean 2
+	Multiplier float64
+	// Classify overrides IsRetryable, if not nil
+	Classify func(error) bool
+}
+
+// Backoff returns Initial * Multiplier ^ (attempt - 1), limited to Max
+func (b ExponentialBackoff) Backoff(attempt uint) time.Duration {
+	m := b.Multiplier
+
+	if m < 1 {
+		m = 2
+	}
+
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	d := float64(b.Initial) * math.Pow(m, float64(attempt-1))
+
+	if b.Max > 0 && d > float64(b.Max) {
+		return b.Max
+	}
+
+	if d > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration(d)
+}
+
+// Retryable reports if err may be retried
+func (b ExponentialBackoff) Retryable(err error) bool {
+	return classify(b.Classify, err)
+}
+
+// DecorrelatedJitter waits a random time between Base and three times the previous upper bound, limited to Cap
+// It spreads the attempts of many clients that lost connection at the same time
+// As policies are shared by concurrent operations, the previous upper bound is derived from the attempt number
+type DecorrelatedJitter struct {
+	Base time.Duration
+	// Cap limits the wait; 0 means no limit
+	Cap time.Duration
+	// Classify overrides IsRetryable, if not nil
+	Classify func(error) bool
+}
+
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Backoff returns a random time between Base and min(Cap, Base * 3 ^ attempt)
+func (b DecorrelatedJitter) Backoff(attempt uint) time.Duration {
+	upper := ExponentialBackoff{Initial: b.Base, Max: b.Cap, Multiplier: 3}.Backoff(attempt + 1)
+
+	if upper <= b.Base {
+		return upper
+	}
+
+	jitterMu.Lock()
+
+	defer jitterMu.Unlock()
+
+	return b.Base + time.Duration(jitterRand.Int63n(int64(upper-b.Base)))
+}
+
+// Retryable reports if err may be retried
+func (b DecorrelatedJitter) Retryable(err error) bool {
+	return classify(b.Classify, err)
+}
@@ -0,0 +1,107 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestIsRetryable(t *testing.T) {
+	// the error of a real operation on a server that's down
+	_, unavailable := unreachableLink(t).currentClient().Database(testDB).Collection(testCollection).CountDocuments(context.Background(), bson.M{})
+
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("anything"), false},
+		{context.DeadlineExceeded, false},
+		{mongo.ErrClientDisconnected, true},
+		{fmt.Errorf("wrapped: %w", mongo.ErrClientDisconnected), true},
+		{mongo.CommandError{Code: 10107, Name: "NotWritablePrimary"}, true},
+		{mongo.CommandError{Code: 11000, Name: "DuplicateKey"}, false},
+		{mongo.CommandError{Labels: []string{"TransientTransactionError"}}, true},
+		{mongo.WriteException{WriteConcernError: &mongo.WriteConcernError{Code: 91}}, true},
+		{mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}, false},
+		{unavailable, true},
+	}
+
+	for _, c := range cases {
+		if got := IsRetryable(c.err); got != c.want {
+			t.Errorf("IsRetryable(%v) = %v, expected %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	if d := (ConstantBackoff{Interval: time.Second}).Backoff(7); d != time.Second {
+		t.Errorf("constant: expected 1s, got %s", d)
+	}
+
+	exp := ExponentialBackoff{Initial: 100 * time.Millisecond, Max: time.Second}
+
+	for attempt, want := range map[uint]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second, 500: time.Second} {
+		if d := exp.Backoff(attempt); d != want {
+			t.Errorf("exponential attempt %d: expected %s, got %s", attempt, want, d)
+		}
+	}
+
+	jitter := DecorrelatedJitter{Base: 100 * time.Millisecond, Cap: time.Second}
+
+	for attempt := uint(1); attempt < 20; attempt++ {
+		if d := jitter.Backoff(attempt); d < jitter.Base || d > jitter.Cap {
+			t.Errorf("jitter attempt %d: %s out of bounds", attempt, d)
+		}
+	}
+
+	classified := ConstantBackoff{Classify: func(err error) bool { return err != nil }}
+
+	if !classified.Retryable(errors.New("anything")) {
+		t.Error("expected Classify to override the default classification")
+	}
+}
+
+func TestLink_retryWrites(t *testing.T) {
+	l := unreachableLink(t)
+
+	defer l.Disconnect()
+
+	l.options.operationRetries = 3
+
+	network := mongo.CommandError{Code: 9001, Name: "SocketException", Labels: []string{"NetworkError"}}
+
+	attempts := func(kind OpKind, err error) int {
+		n := 0
+
+		_, _ = l.run(context.Background(), &Operation{Kind: kind, Database: testDB, Collection: testCollection}, func(context.Context, *mongo.Client, *Operation) (*OpResult, error) {
+			n++
+
+			return nil, err
+		})
+
+		return n
+	}
+
+	// the server may have applied the write before the failure
+	for _, kind := range []OpKind{OpInsertOne, OpInsertMany, OpUpdateMany, OpDeleteMany, OpBulkWrite} {
+		if n := attempts(kind, network); n != 1 {
+			t.Errorf("%s: expected a single attempt on a network error, got %d", kind, n)
+		}
+	}
+
+	if n := attempts(OpFind, network); n != 4 {
+		t.Errorf("expected reads to be retried on a network error, got %d attempts", n)
+	}
+
+	_, unavailable := l.currentClient().Database(testDB).Collection(testCollection).CountDocuments(context.Background(), bson.M{})
+
+	if n := attempts(OpInsertOne, unavailable); n != 4 {
+		t.Errorf("expected writes to be retried when no server was selected, got %d attempts", n)
+	}
+}
@@ -0,0 +1,52 @@
+package mongohelper
+
+import "sync/atomic"
+
+// State describes the health of the link with database, as seen by the engine
+type State int32
+
+const (
+	// StateConnecting means a (re)connection is in progress
+	StateConnecting State = iota
+	// StateConnected means the last connection attempt or ping succeeded
+	StateConnected
+	// StateDegraded means the connection is lost and the engine gave up, or is waiting, to reconnect
+	StateDegraded
+	// StateClosed means Disconnect() was called
+	StateClosed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateDegraded:
+		return "degraded"
+	case StateClosed:
+		return "closed"
+	}
+
+	return "unknown"
+}
+
+// State returns the current state of the link with database
+func (l *Link) State() State {
+	return State(atomic.LoadInt32(&l.state))
+}
+
+// setState changes the current state, unless the link is already closed
+func (l *Link) setState(s State) {
+	for {
+		current := atomic.LoadInt32(&l.state)
+
+		if State(current) == StateClosed {
+			return
+		}
+
+		if atomic.CompareAndSwapInt32(&l.state, current, int32(s)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package mongohelper
+
+import "context"
+
+// Store is the set of operations offered by Link
+// Code that depends on Store instead of *Link can be tested against a MemoryStore, with no database at all
+type Store interface {
+	Find(database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error
+	FindCtx(ctx context.Context, database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error
+	FindOne(database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error
+	FindOneCtx(ctx context.Context, database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error
+	InsertOne(database, collection string, document interface{}) (string, error)
+	InsertOneCtx(ctx context.Context, database, collection string, document interface{}) (string, error)
+	InsertMany(database, collection string, document []interface{}) ([]string, error)
+	InsertManyCtx(ctx context.Context, database, collection string, document []interface{}) ([]string, error)
+	UpdateOne(database, collection string, filter, update interface{}) (int64, error)
+	UpdateOneCtx(ctx context.Context, database, collection string, filter, update interface{}) (int64, error)
+	UpdateMany(database, collection string, filter, update interface{}) (int64, error)
+	UpdateManyCtx(ctx context.Context, database, collection string, filter, update interface{}) (int64, error)
+	ReplaceOne(database, collection string, filter, replacement interface{}) (int64, error)
+	ReplaceOneCtx(ctx context.Context, database, collection string, filter, replacement interface{}) (int64, error)
+	DeleteOne(database, collection string, filter interface{}) (int64, error)
+	DeleteOneCtx(ctx context.Context, database, collection string, filter interface{}) (int64, error)
+	DeleteMany(database, collection string, filter interface{}) (int64, error)
+	DeleteManyCtx(ctx context.Context, database, collection string, filter interface{}) (int64, error)
+	CountDocs(database, collection string, filter interface{}, opts ...FindOption) (int64, error)
+	CountDocsCtx(ctx context.Context, database, collection string, filter interface{}, opts ...FindOption) (int64, error)
+	Paginate(database, collection string, filter interface{}, req PageRequest, dest interface{}) (*Page, error)
+	PaginateCtx(ctx context.Context, database, collection string, filter interface{}, req PageRequest, dest interface{}) (*Page, error)
+}
+
+// Link and MemoryStore must keep offering the same API
+var (
+	_ Store = (*Link)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
@@ -0,0 +1,119 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// supervisorFailedPings is how many pings in a row must fail before the supervisor replaces the client
+const supervisorFailedPings = 3
+
+// supervisor is the background routine that watches the link health, replacing the client when it's lost
+type supervisor struct {
+	interval time.Duration
+	// failedPings is how many pings in a row must fail before reconnecting
+	failedPings uint
+	// cancel stops the routine
+	cancel context.CancelFunc
+	// done is closed when the routine returns
+	done chan struct{}
+	once sync.Once
+}
+
+// supervise starts the supervisor routine, pinging database every interval
+func (l *Link) supervise(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	l.supervisor = &supervisor{
+		interval:    interval,
+		failedPings: supervisorFailedPings,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+
+	go l.supervisor.run(ctx, l)
+}
+
+func (s *supervisor) run(ctx context.Context, l *Link) {
+	defer close(s.done)
+
+	t := time.NewTicker(s.interval)
+
+	defer t.Stop()
+
+	// failures counts the failed pings in a row, and gaveUp tells if the reconnection budget ran out in this outage
+	var (
+		failures uint
+		gaveUp   bool
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		client := l.currentClient()
+
+		err := l.ping(ctx)
+
+		if err == nil {
+			if failures > 0 || l.State() != StateConnected {
+				l.notifyConnection()
+			}
+
+			failures, gaveUp = 0, false
+
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		failures++
+
+		l.emit(EventPingFailed, "link.supervisor", err)
+
+		l.setState(StateDegraded)
+
+		// the driver may recover a live client by itself, so it's only replaced after some failed pings
+		if failures < s.failedPings && !errors.Is(err, mongo.ErrClientDisconnected) {
+			l.logger().Warn("ping failed, waiting for the driver to recover", Field{FieldRoutine, "link.supervisor"}, Field{FieldAttempt, failures}, Field{FieldError, err})
+
+			continue
+		}
+
+		if !l.canInsist() {
+			if !gaveUp {
+				gaveUp = true
+
+				l.logger().Error("reconnection limits reached, giving up", Field{FieldRoutine, "link.supervisor"}, Field{FieldAttempt, l.tracker.status().attempts}, Field{FieldError, err})
+
+				l.emit(EventReconnectGaveUp, "link.supervisor", err)
+			}
+
+			continue
+		}
+
+		l.logger().Warn("connection lost, reconnecting", Field{FieldRoutine, "link.supervisor"}, Field{FieldError, err})
+
+		// every attempt is recorded by the tracker, that enforces the attempt and duration limits
+		if err := l.reconnect(ctx, l.insistOnFail(), client); err != nil {
+			l.logger().Error("reconnection failed, retrying on next check", Field{FieldRoutine, "link.supervisor"}, Field{FieldError, err})
+		}
+	}
+}
+
+// stop ends the supervisor routine, waiting for it to return. It's safe to call more than once
+func (s *supervisor) stop() {
+	s.once.Do(func() {
+		s.cancel()
+		<-s.done
+	})
+}
@@ -0,0 +1,179 @@
+package mongohelper
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// unreachableLink returns a link whose client points to a closed port, with very short timeouts
+func unreachableLink(t *testing.T) *Link {
+	t.Helper()
+
+	l := linkNew(*NewOptions("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=50"))
+	l.options.connTimeout = 50 * time.Millisecond
+	l.options.reconnectionInterval = 10 * time.Millisecond
+
+	if err := l.connectCtx(context.Background(), false); err == nil {
+		t.Fatal("expected connection to fail")
+	}
+
+	return l
+}
+
+// eventLog records the events of a link
+type eventLog struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventLog) add(e Event) {
+	r.mu.Lock()
+
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, e)
+}
+
+// count returns how many events of the given routine and type were recorded
+func (r *eventLog) count(routine string, t EventType) int {
+	r.mu.Lock()
+
+	defer r.mu.Unlock()
+
+	n := 0
+
+	for _, e := range r.events {
+		if e.Routine == routine && e.Type == t {
+			n++
+		}
+	}
+
+	return n
+}
+
+// wait waits until an event of the given routine and type is recorded
+func (r *eventLog) wait(t *testing.T, routine string, typ EventType) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for r.count(routine, typ) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no %s event from %s", typ, routine)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLink_supervisor(t *testing.T) {
+	l := unreachableLink(t)
+
+	if s := l.State(); s != StateDegraded {
+		t.Fatalf("expected state %s, got %s", StateDegraded, s)
+	}
+
+	// a fresh budget of two reconnection attempts
+	l.tracker = newReconnectTracker(2, 0, nil)
+
+	var log eventLog
+
+	defer l.Subscribe(log.add)()
+
+	client := l.currentClient()
+
+	l.supervise(10 * time.Millisecond)
+
+	log.wait(t, "link.supervisor", EventReconnectGaveUp)
+
+	// the client is replaced only after some failed pings in a row
+	log.mu.Lock()
+
+	pings := 0
+
+	for _, e := range log.events {
+		if e.Routine == "link.connect" {
+			break
+		}
+
+		pings++
+	}
+
+	log.mu.Unlock()
+
+	if pings != supervisorFailedPings {
+		t.Errorf("expected the first reconnection after %d failed pings, got %d", supervisorFailedPings, pings)
+	}
+
+	if l.currentClient() == client {
+		t.Error("expected the client to be replaced")
+	}
+
+	// once the attempts run out, the supervisor keeps pinging without reconnecting
+	time.Sleep(100 * time.Millisecond)
+
+	if n := log.count("link.connect", EventPingFailed); n != 2 {
+		t.Errorf("expected 2 reconnection attempts, got %d", n)
+	}
+
+	if n := log.count("link.supervisor", EventReconnectGaveUp); n != 1 {
+		t.Errorf("expected to give up once, got %d", n)
+	}
+
+	if s := l.State(); s != StateDegraded {
+		t.Errorf("expected state %s, got %s", StateDegraded, s)
+	}
+
+	l.Disconnect()
+	l.Disconnect()
+
+	if s := l.State(); s != StateClosed {
+		t.Errorf("expected state %s, got %s", StateClosed, s)
+	}
+
+	if _, err := l.CountDocs(testDB, testCollection, nil); err == nil {
+		t.Error("expected an error using a closed link")
+	}
+}
+
+func TestLink_supervisorDuration(t *testing.T) {
+	l := unreachableLink(t)
+
+	clock := &fakeClock{t: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	// a fresh budget of one minute of outage, and no attempts limit
+	l.tracker = newReconnectTracker(0, time.Minute, clock.now)
+
+	var log eventLog
+
+	defer l.Subscribe(log.add)()
+
+	l.supervise(10 * time.Millisecond)
+
+	defer l.Disconnect()
+
+	// a disconnected client is replaced at once
+	_ = l.currentClient().Disconnect(context.Background())
+
+	log.wait(t, "link.connect", EventPingFailed)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if log.count("link.supervisor", EventReconnectGaveUp) != 0 {
+		t.Fatal("expected the supervisor to keep reconnecting within the time limit")
+	}
+
+	clock.advance(time.Minute)
+
+	log.wait(t, "link.supervisor", EventReconnectGaveUp)
+
+	attempts := log.count("link.connect", EventPingFailed)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if n := log.count("link.connect", EventPingFailed); n != attempts {
+		t.Errorf("expected no attempts after the time limit, got %d more", n-attempts)
+	}
+}
@@ -0,0 +1,196 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// Tracer starts the spans that describe operations, retries, reconnections and raw driver commands
+// It's a small subset of OpenTelemetry's tracer, so adapting one takes a few lines. See RecordingTracer
+type Tracer interface {
+	// Start begins a span, child of the span held by ctx if any, and returns a context that holds the new span
+	Start(ctx context.Context, name string, attrs ...Field) (context.Context, Span)
+}
+
+// Span is a timed unit of work, created by a Tracer
+type Span interface {
+	SetAttributes(attrs ...Field)
+	AddEvent(name string, attrs ...Field)
+	RecordError(err error)
+	End()
+}
+
+// Attribute keys attached to spans, following the OpenTelemetry database semantic conventions
+const (
+	AttrDBSystem     = "db.system"
+	AttrDBName       = "db.name"
+	AttrDBCollection = "db.mongodb.collection"
+	AttrDBOperation  = "db.operation"
+	// AttrDBStatement holds the filter and update with every value replaced by "?"
+	AttrDBStatement = "db.statement"
+	// AttrDBDuration holds the command duration measured by the driver
+	AttrDBDuration = "db.mongodb.duration"
+)
+
+// NopTracer starts spans that discard everything
+type NopTracer struct{}
+
+// Start returns ctx and a span that discards everything
+func (NopTracer) Start(ctx context.Context, _ string, _ ...Field) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Field)    {}
+func (nopSpan) AddEvent(string, ...Field) {}
+func (nopSpan) RecordError(error)         {}
+func (nopSpan) End()                      {}
+
+// tracing reports if a Tracer other than NopTracer is configured
+func (l *Link) tracing() bool {
+	_, nop := l.tracer().(NopTracer)
+
+	return !nop
+}
+
+// spanKey holds the current span in contexts created by startSpan
+type spanKey struct{}
+
+// startSpan starts a span with the configured tracer, keeping it in the returned context for spanFromContext
+func (l *Link) startSpan(ctx context.Context, name string, attrs ...Field) (context.Context, Span) {
+	ctx, span := l.tracer().Start(ctx, name, attrs...)
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// spanFromContext returns the span started by startSpan, or a span that discards everything
+func spanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanKey{}).(Span); ok {
+		return span
+	}
+
+	return nopSpan{}
+}
+
+// endSpan records err, unless it's an expected outcome, and ends the span
+func endSpan(span Span, err error) {
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		span.RecordError(err)
+	}
+
+	span.End()
+}
+
+// commandMonitor returns a driver monitor that traces every command sent to the server
+// Command spans are children of the operation span found in the command context
+func (l *Link) commandMonitor() *event.CommandMonitor {
+	var spans sync.Map
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			if !l.tracing() {
+				return
+			}
+
+			_, span := l.tracer().Start(ctx, e.CommandName, Field{AttrDBSystem, "mongodb"}, Field{AttrDBName, e.DatabaseName}, Field{AttrDBOperation, e.CommandName})
+
+			spans.Store(e.RequestID, span)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			if span, ok := spans.LoadAndDelete(e.RequestID); ok {
+				span.(Span).SetAttributes(Field{AttrDBDuration, time.Duration(e.DurationNanos)})
+				span.(Span).End()
+			}
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			if span, ok := spans.LoadAndDelete(e.RequestID); ok {
+				span.(Span).SetAttributes(Field{AttrDBDuration, time.Duration(e.DurationNanos)})
+				span.(Span).RecordError(errors.New(e.Failure))
+				span.(Span).End()
+			}
+		},
+	}
+}
+
+// attributes returns the span attributes that describe op
+func (op *Operation) attributes() []Field {
+	attrs := []Field{{AttrDBSystem, "mongodb"}, {AttrDBName, op.Database}, {AttrDBCollection, op.Collection}, {AttrDBOperation, string(op.Kind)}}
+
+	if s := op.statement(); s != "" {
+		attrs = append(attrs, Field{AttrDBStatement, s})
+	}
+
+	return attrs
+}
+
+// statement returns op's filter, update and pipeline as extended JSON, with every value replaced by "?"
+// so no data reaches the traces. It's empty when op has neither, or they can't be encoded
+func (op *Operation) statement() string {
+	var d bson.D
+
+	for _, e := range []bson.E{{Key: "filter", Value: op.Filter}, {Key: "update", Value: op.Update}} {
+		if e.Value == nil {
+			continue
+		}
+
+		doc, err := toDoc(e.Value)
+
+		if err != nil {
+			return ""
+		}
+
+		d = append(d, bson.E{Key: e.Key, Value: sanitize(doc)})
+	}
+
+	if op.Pipeline != nil {
+		doc, err := toDoc(bson.D{{Key: "pipeline", Value: op.Pipeline}})
+
+		if err != nil {
+			return ""
+		}
+
+		d = append(d, bson.E{Key: "pipeline", Value: sanitize(doc[0].Value)})
+	}
+
+	if d == nil {
+		return ""
+	}
+
+	b, err := bson.MarshalExtJSON(d, false, false)
+
+	if err != nil {
+		return ""
+	}
+
+	return string(b)
+}
+
+// sanitize keeps the structure of v, replacing every value by "?"
+func sanitize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case bson.D:
+		c := make(bson.D, len(x))
+
+		for i, e := range x {
+			c[i] = bson.E{Key: e.Key, Value: sanitize(e.Value)}
+		}
+
+		return c
+	case bson.A:
+		c := make(bson.A, len(x))
+
+		for i, item := range x {
+			c[i] = sanitize(item)
+		}
+
+		return c
+	}
+
+	return "?"
+}
@@ -0,0 +1,100 @@
+package mongohelper
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func TestLink_tracing(t *testing.T) {
+	// the statement isn't even built without a tracer
+	if (&Link{}).tracing() || linkNew(*NewOptions(testConnectionString)).tracing() {
+		t.Error("expected no tracing by default")
+	}
+
+	tracer := RecordingTracerNew()
+
+	l := linkNew(*NewOptions("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=50", WithTracer(tracer)))
+	l.options.connTimeout = 50 * time.Millisecond
+	l.options.reconnectionInterval = 10 * time.Millisecond
+
+	if err := l.connectCtx(context.Background(), false); err == nil {
+		t.Fatal("expected connection to fail")
+	}
+
+	// simulates a client closed underneath the link, so the operation triggers a reconnection
+	if err := l.currentClient().Disconnect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := l.CountDocs(testDB, testCollection, bson.M{"name": "secret"}); err == nil {
+		t.Fatal("expected the count to fail")
+	}
+
+	spans := tracer.Spans()
+
+	if len(spans) != 2 {
+		t.Fatalf("expected an operation and a reconnect span, got %+v", spans)
+	}
+
+	op, rc := spans[0], spans[1]
+
+	if op.Name != "CountDocs "+testDB+"."+testCollection || op.ParentID != 0 || op.End.IsZero() || len(op.Errors) != 1 {
+		t.Errorf("unexpected operation span %+v", op)
+	}
+
+	for key, want := range map[string]string{AttrDBSystem: "mongodb", AttrDBName: testDB, AttrDBCollection: testCollection, AttrDBOperation: "CountDocs"} {
+		if v, _ := op.Attribute(key); v != want {
+			t.Errorf("expected %s %q, got %v", key, want, v)
+		}
+	}
+
+	if v, _ := op.Attribute(AttrDBStatement); !strings.Contains(v.(string), `"name":"?"`) || strings.Contains(v.(string), "secret") {
+		t.Errorf("expected a sanitized statement, got %v", v)
+	}
+
+	if rc.Name != "reconnect" || rc.ParentID != op.ID || len(rc.Errors) != 1 || rc.End.IsZero() {
+		t.Errorf("expected a failed reconnect span, child of the operation span, got %+v", rc)
+	}
+}
+
+func TestLink_commandMonitor(t *testing.T) {
+	tracer := RecordingTracerNew()
+
+	l := linkNew(*NewOptions("mongodb://127.0.0.1:1", WithTracer(tracer)))
+
+	ctx, op := l.startSpan(context.Background(), "Find")
+
+	m := l.commandMonitor()
+
+	m.Started(ctx, &event.CommandStartedEvent{DatabaseName: testDB, CommandName: "find", RequestID: 1})
+	m.Started(ctx, &event.CommandStartedEvent{DatabaseName: testDB, CommandName: "getMore", RequestID: 2})
+	m.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: event.CommandFinishedEvent{DurationNanos: int64(time.Millisecond), CommandName: "find", RequestID: 1}})
+	m.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "getMore", RequestID: 2}, Failure: "cursor killed"})
+
+	op.End()
+
+	spans := tracer.Spans()
+
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %+v", spans)
+	}
+
+	for _, s := range spans[1:] {
+		if s.ParentID != spans[0].ID || s.End.IsZero() {
+			t.Errorf("expected an ended child of the operation span, got %+v", s)
+		}
+	}
+
+	if d, _ := spans[1].Attribute(AttrDBDuration); d != time.Millisecond || len(spans[1].Errors) != 0 {
+		t.Errorf("expected a successful command of 1ms, got %+v", spans[1])
+	}
+
+	if len(spans[2].Errors) != 1 || spans[2].Errors[0].Error() != "cursor killed" {
+		t.Errorf("expected the command failure, got %+v", spans[2])
+	}
+}
@@ -0,0 +1,98 @@
+package mongohelper
+
+import (
+	"sync"
+	"time"
+)
+
+// reconnectTracker keeps the (re)connection budget: how many attempts failed, and since when the outage lasts
+// It's safe for concurrent use
+type reconnectTracker struct {
+	mu sync.Mutex
+	// now returns the current time. tests replace it to control the clock
+	now func() time.Time
+	// maxAttempts is the maximum number of failed attempts per outage, or 0 for infinite
+	maxAttempts uint
+	// maxDuration is the maximum time trying to (re)connect since the outage began, or 0 for infinite
+	maxDuration time.Duration
+	// attempts is the number of failed attempts since the outage began
+	attempts uint
+	// outageStart is when the first attempt of the current outage failed, or zero if there's no outage
+	outageStart time.Time
+	// lastConnection is when the last connection succeeded
+	lastConnection time.Time
+}
+
+// newReconnectTracker returns a tracker enforcing the given limits. If now is nil, time.Now is used
+func newReconnectTracker(maxAttempts uint, maxDuration time.Duration, now func() time.Time) *reconnectTracker {
+	if now == nil {
+		now = time.Now
+	}
+
+	return &reconnectTracker{
+		now:         now,
+		maxAttempts: maxAttempts,
+		maxDuration: maxDuration,
+	}
+}
+
+// failure records a failed attempt, starting a new outage if there's none
+func (t *reconnectTracker) failure() {
+	t.mu.Lock()
+
+	defer t.mu.Unlock()
+
+	if t.outageStart.IsZero() {
+		t.outageStart = t.now()
+	}
+
+	t.attempts++
+}
+
+// success records a successful connection, ending the current outage
+func (t *reconnectTracker) success() {
+	t.mu.Lock()
+
+	defer t.mu.Unlock()
+
+	t.attempts = 0
+	t.outageStart = time.Time{}
+	t.lastConnection = t.now()
+}
+
+// canRetry reports if the budget allows another attempt in the current outage
+func (t *reconnectTracker) canRetry() bool {
+	t.mu.Lock()
+
+	defer t.mu.Unlock()
+
+	if t.maxAttempts > 0 && t.attempts >= t.maxAttempts {
+		return false
+	}
+
+	if t.maxDuration > 0 && !t.outageStart.IsZero() && t.now().Sub(t.outageStart) >= t.maxDuration {
+		return false
+	}
+
+	return true
+}
+
+// trackerStatus is a consistent copy of the tracker counters
+type trackerStatus struct {
+	attempts       uint
+	outageStart    time.Time
+	lastConnection time.Time
+}
+
+// status returns the current counters
+func (t *reconnectTracker) status() trackerStatus {
+	t.mu.Lock()
+
+	defer t.mu.Unlock()
+
+	return trackerStatus{
+		attempts:       t.attempts,
+		outageStart:    t.outageStart,
+		lastConnection: t.lastConnection,
+	}
+}
@@ -0,0 +1,122 @@
+package mongohelper
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+
+	defer c.mu.Unlock()
+
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+
+	defer c.mu.Unlock()
+
+	c.t = c.t.Add(d)
+}
+
+func TestReconnectTracker_attempts(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)}
+	tr := newReconnectTracker(3, 0, clock.now)
+
+	for i := 1; i <= 3; i++ {
+		if !tr.canRetry() {
+			t.Fatalf("expected attempt %d to be allowed", i)
+		}
+
+		tr.failure()
+		clock.advance(time.Hour)
+	}
+
+	if tr.canRetry() {
+		t.Error("expected the budget to be exhausted after 3 failed attempts")
+	}
+
+	tr.success()
+
+	if !tr.canRetry() || tr.status().attempts != 0 {
+		t.Error("expected the budget to be restored after a success")
+	}
+
+	if got := tr.status().lastConnection; !got.Equal(clock.now()) {
+		t.Errorf("expected last connection at %s, got %s", clock.now(), got)
+	}
+}
+
+func TestReconnectTracker_duration(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)}
+	tr := newReconnectTracker(0, 5*time.Minute, clock.now)
+
+	// the outage begins on the first failure, not on the last connection, which never happened
+	clock.advance(time.Hour)
+	tr.failure()
+
+	if s := tr.status(); !s.outageStart.Equal(clock.now()) {
+		t.Errorf("expected the outage to begin at %s, got %s", clock.now(), s.outageStart)
+	}
+
+	clock.advance(4 * time.Minute)
+	tr.failure()
+
+	if !tr.canRetry() {
+		t.Error("expected retries to be allowed within the time limit")
+	}
+
+	clock.advance(time.Minute)
+
+	if tr.canRetry() {
+		t.Error("expected retries to be denied after the time limit")
+	}
+}
+
+func TestReconnectTracker_infinite(t *testing.T) {
+	clock := &fakeClock{}
+	tr := newReconnectTracker(0, 0, clock.now)
+
+	for i := 0; i < 1000; i++ {
+		tr.failure()
+		clock.advance(time.Hour)
+	}
+
+	if !tr.canRetry() {
+		t.Error("expected no limits when both are 0")
+	}
+}
+
+func TestReconnectTracker_concurrent(t *testing.T) {
+	tr := newReconnectTracker(100, 0, nil)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 10; j++ {
+				tr.canRetry()
+				tr.failure()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if n := tr.status().attempts; n != 100 || tr.canRetry() {
+		t.Errorf("expected 100 attempts and an exhausted budget, got %d", n)
+	}
+}
@@ -0,0 +1,317 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// DefaultTxTimeout limits the retries of WithTransaction when TxOptions.Timeout isn't set
+const DefaultTxTimeout = 120 * time.Second
+
+// TxOptions tunes WithTransaction. nil concerns and preference mean the client defaults
+type TxOptions struct {
+	// ReadConcern is the read concern of the transaction, like readconcern.Snapshot()
+	ReadConcern *readconcern.ReadConcern
+	// WriteConcern is the write concern of the commit, like writeconcern.New(writeconcern.WMajority())
+	WriteConcern *writeconcern.WriteConcern
+	// ReadPreference must be primary for transactions with reads
+	ReadPreference *readpref.ReadPref
+	// MaxCommitTime limits the server side commit time
+	MaxCommitTime time.Duration
+	// Timeout stops the retries once elapsed since the first attempt. Defaults to DefaultTxTimeout
+	Timeout time.Duration
+}
+
+// transaction returns the driver options of the transaction
+func (o TxOptions) transaction() *options.TransactionOptions {
+	t := options.Transaction()
+
+	if o.ReadConcern != nil {
+		t.SetReadConcern(o.ReadConcern)
+	}
+
+	if o.WriteConcern != nil {
+		t.SetWriteConcern(o.WriteConcern)
+	}
+
+	if o.ReadPreference != nil {
+		t.SetReadPreference(o.ReadPreference)
+	}
+
+	if o.MaxCommitTime > 0 {
+		t.SetMaxCommitTime(&o.MaxCommitTime)
+	}
+
+	return t
+}
+
+// TxLink is the view of a Link inside a transaction. Every operation done through it is part of the transaction
+// It's a Store, so repositories can work inside transactions too: RepositoryNew[T](tx, database, collection)
+type TxLink interface {
+	Store
+	Aggregate(database, collection string, pipeline interface{}, dest interface{}) error
+	AggregateCtx(ctx context.Context, database, collection string, pipeline interface{}, dest interface{}) error
+	BulkWrite(database, collection string, models []WriteModel, opts BulkOptions) (*BulkResult, error)
+	BulkWriteCtx(ctx context.Context, database, collection string, models []WriteModel, opts BulkOptions) (*BulkResult, error)
+}
+
+// txKey holds, in contexts of operations running inside a transaction, the client the transaction runs on
+type txKey struct{}
+
+// inTransaction reports if ctx belongs to an operation running inside a transaction
+// Such operations are never retried nor reconnected by themselves: WithTransaction retries the whole transaction
+func inTransaction(ctx context.Context) bool {
+	return txClient(ctx) != nil
+}
+
+// txClient returns the client of the transaction ctx belongs to, or nil outside transactions
+// The session only works on the client that started it, even if a reconnection replaced it meanwhile
+func txClient(ctx context.Context) *mongo.Client {
+	client, _ := ctx.Value(txKey{}).(*mongo.Client)
+
+	return client
+}
+
+// hasErrorLabel reports if err, or any error it wraps, is a server error with the given label
+func hasErrorLabel(err error, label string) bool {
+	var ce mongo.CommandError
+
+	return errors.As(err, &ce) && ce.HasErrorLabel(label)
+}
+
+// WithTransaction runs fn inside a multi-document transaction, committing it when fn returns nil and aborting it
+// otherwise. fn must do every operation through tx, and may run more than once: the whole transaction is retried
+// when it fails with the TransientTransactionError label, or on a disconnected client, and the commit is retried on
+// the UnknownTransactionCommitResult label, until opts.Timeout elapses
+// Transactions need a replica set or a sharded cluster
+func (l *Link) WithTransaction(ctx context.Context, fn func(tx TxLink) error, opts TxOptions) error {
+	const routine = "link.WithTransaction"
+
+	if err := l.linkCheck(routine, "", ""); err != nil {
+		return err
+	}
+
+	timeout := opts.Timeout
+
+	if timeout <= 0 {
+		timeout = DefaultTxTimeout
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for attempt := uint(1); ; attempt++ {
+		client := l.currentClient()
+
+		start := time.Now()
+
+		err := l.transaction(ctx, client, fn, opts, deadline)
+
+		fields := []Field{{FieldRoutine, routine}, {FieldDuration, time.Since(start)}, {FieldAttempt, attempt}}
+
+		if err == nil {
+			l.logger().Debug("transaction committed", fields...)
+
+			return nil
+		}
+
+		if time.Now().After(deadline) || ctx.Err() != nil {
+			l.logger().Error("transaction failed", append(fields, Field{FieldError, err})...)
+
+			return opError(routine, "", "", attempt, err)
+		}
+
+		switch {
+		case hasErrorLabel(err, "TransientTransactionError"):
+		case errors.Is(err, mongo.ErrClientDisconnected):
+			if err := l.reconnect(ctx, l.insistOnFail(), client); err != nil {
+				return &OpError{Op: opName(routine), Attempts: attempt, Kind: ErrReconnectFailed, Err: err}
+			}
+		default:
+			l.logger().Error("transaction failed", append(fields, Field{FieldError, err})...)
+
+			return opError(routine, "", "", attempt, err)
+		}
+
+		l.logger().Warn("transaction failed, retrying", append(fields, Field{FieldError, err})...)
+
+		l.emit(EventOperationRetried, routine, err)
+	}
+}
+
+// transaction runs one attempt of WithTransaction, on a session of its own
+func (l *Link) transaction(ctx context.Context, client *mongo.Client, fn func(tx TxLink) error, opts TxOptions, deadline time.Time) error {
+	sess, err := client.StartSession()
+
+	if err != nil {
+		return err
+	}
+
+	defer sess.EndSession(context.Background())
+
+	if err := sess.StartTransaction(opts.transaction()); err != nil {
+		return err
+	}
+
+	return mongo.WithSession(ctx, sess, func(sctx mongo.SessionContext) error {
+		if err := fn(&txLink{link: l, client: client, sess: sess, ctx: context.WithValue(sctx, txKey{}, client)}); err != nil {
+			// the transaction context may be already done, and the abort must still reach the server
+			actx, cancel := context.WithTimeout(context.Background(), l.execTimeout())
+
+			defer cancel()
+
+			_ = sess.AbortTransaction(actx)
+
+			return err
+		}
+
+		for {
+			err := sess.CommitTransaction(sctx)
+
+			if err == nil || !hasErrorLabel(err, "UnknownTransactionCommitResult") || time.Now().After(deadline) || ctx.Err() != nil {
+				return err
+			}
+		}
+	})
+}
+
+// txLink implements TxLink, running every Link operation within the transaction session, on its client
+type txLink struct {
+	link *Link
+	// client is the client the session was started on
+	client *mongo.Client
+	sess   mongo.Session
+	// ctx holds the session, for the operations that don't take a context
+	ctx context.Context
+}
+
+// within returns ctx holding the transaction session, so the operation joins the transaction
+func (t *txLink) within(ctx context.Context) context.Context {
+	if ctx == t.ctx {
+		return ctx
+	}
+
+	var sctx context.Context
+
+	// WithSession is the only public way to attach a session to a context
+	_ = mongo.WithSession(ctx, t.sess, func(s mongo.SessionContext) error {
+		sctx = s
+
+		return nil
+	})
+
+	return context.WithValue(sctx, txKey{}, t.client)
+}
+
+func (t *txLink) Find(database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error {
+	return t.FindCtx(t.ctx, database, collection, filter, dest, opts...)
+}
+
+func (t *txLink) FindCtx(ctx context.Context, database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error {
+	return t.link.FindCtx(t.within(ctx), database, collection, filter, dest, opts...)
+}
+
+func (t *txLink) FindOne(database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error {
+	return t.FindOneCtx(t.ctx, database, collection, filter, dest, opts...)
+}
+
+func (t *txLink) FindOneCtx(ctx context.Context, database, collection string, filter interface{}, dest interface{}, opts ...FindOption) error {
+	return t.link.FindOneCtx(t.within(ctx), database, collection, filter, dest, opts...)
+}
+
+func (t *txLink) InsertOne(database, collection string, document interface{}) (string, error) {
+	return t.InsertOneCtx(t.ctx, database, collection, document)
+}
+
+func (t *txLink) InsertOneCtx(ctx context.Context, database, collection string, document interface{}) (string, error) {
+	return t.link.InsertOneCtx(t.within(ctx), database, collection, document)
+}
+
+func (t *txLink) InsertMany(database, collection string, documents []interface{}) ([]string, error) {
+	return t.InsertManyCtx(t.ctx, database, collection, documents)
+}
+
+func (t *txLink) InsertManyCtx(ctx context.Context, database, collection string, documents []interface{}) ([]string, error) {
+	return t.link.InsertManyCtx(t.within(ctx), database, collection, documents)
+}
+
+func (t *txLink) UpdateOne(database, collection string, filter, update interface{}) (int64, error) {
+	return t.UpdateOneCtx(t.ctx, database, collection, filter, update)
+}
+
+func (t *txLink) UpdateOneCtx(ctx context.Context, database, collection string, filter, update interface{}) (int64, error) {
+	return t.link.UpdateOneCtx(t.within(ctx), database, collection, filter, update)
+}
+
+func (t *txLink) UpdateMany(database, collection string, filter, update interface{}) (int64, error) {
+	return t.UpdateManyCtx(t.ctx, database, collection, filter, update)
+}
+
+func (t *txLink) UpdateManyCtx(ctx context.Context, database, collection string, filter, update interface{}) (int64, error) {
+	return t.link.UpdateManyCtx(t.within(ctx), database, collection, filter, update)
+}
+
+func (t *txLink) ReplaceOne(database, collection string, filter, replacement interface{}) (int64, error) {
+	return t.ReplaceOneCtx(t.ctx, database, collection, filter, replacement)
+}
+
+func (t *txLink) ReplaceOneCtx(ctx context.Context, database, collection string, filter, replacement interface{}) (int64, error) {
+	return t.link.ReplaceOneCtx(t.within(ctx), database, collection, filter, replacement)
+}
+
+func (t *txLink) DeleteOne(database, collection string, filter interface{}) (int64, error) {
+	return t.DeleteOneCtx(t.ctx, database, collection, filter)
+}
+
+func (t *txLink) DeleteOneCtx(ctx context.Context, database, collection string, filter interface{}) (int64, error) {
+	return t.link.DeleteOneCtx(t.within(ctx), database, collection, filter)
+}
+
+func (t *txLink) DeleteMany(database, collection string, filter interface{}) (int64, error) {
+	return t.DeleteManyCtx(t.ctx, database, collection, filter)
+}
+
+func (t *txLink) DeleteManyCtx(ctx context.Context, database, collection string, filter interface{}) (int64, error) {
+	return t.link.DeleteManyCtx(t.within(ctx), database, collection, filter)
+}
+
+func (t *txLink) CountDocs(database, collection string, filter interface{}, opts ...FindOption) (int64, error) {
+	return t.CountDocsCtx(t.ctx, database, collection, filter, opts...)
+}
+
+func (t *txLink) CountDocsCtx(ctx context.Context, database, collection string, filter interface{}, opts ...FindOption) (int64, error) {
+	return t.link.CountDocsCtx(t.within(ctx), database, collection, filter, opts...)
+}
+
+func (t *txLink) Paginate(database, collection string, filter interface{}, req PageRequest, dest interface{}) (*Page, error) {
+	return t.PaginateCtx(t.ctx, database, collection, filter, req, dest)
+}
+
+func (t *txLink) PaginateCtx(ctx context.Context, database, collection string, filter interface{}, req PageRequest, dest interface{}) (*Page, error) {
+	return t.link.PaginateCtx(t.within(ctx), database, collection, filter, req, dest)
+}
+
+func (t *txLink) Aggregate(database, collection string, pipeline interface{}, dest interface{}) error {
+	return t.AggregateCtx(t.ctx, database, collection, pipeline, dest)
+}
+
+func (t *txLink) AggregateCtx(ctx context.Context, database, collection string, pipeline interface{}, dest interface{}) error {
+	return t.link.AggregateCtx(t.within(ctx), database, collection, pipeline, dest)
+}
+
+func (t *txLink) BulkWrite(database, collection string, models []WriteModel, opts BulkOptions) (*BulkResult, error) {
+	return t.BulkWriteCtx(t.ctx, database, collection, models, opts)
+}
+
+func (t *txLink) BulkWriteCtx(ctx context.Context, database, collection string, models []WriteModel, opts BulkOptions) (*BulkResult, error) {
+	return t.link.BulkWriteCtx(t.within(ctx), database, collection, models, opts)
+}
+
+// txLink must keep offering the whole TxLink API
+var _ TxLink = (*txLink)(nil)
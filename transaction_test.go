@@ -0,0 +1,116 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+func TestLink_WithTransaction(t *testing.T) {
+	var l Link
+
+	called := false
+
+	err := l.WithTransaction(context.Background(), func(tx TxLink) error {
+		called = true
+
+		return nil
+	}, TxOptions{})
+
+	if !errors.Is(err, ErrNotConnected) || called {
+		t.Errorf("expected ErrNotConnected without running the callback, got %v", err)
+	}
+}
+
+func TestLink_inTransaction(t *testing.T) {
+	l := unreachableLink(t)
+
+	l.options.operationRetries = 2
+	l.options.retryPolicy = ConstantBackoff{Interval: time.Millisecond, Classify: func(error) bool { return true }}
+
+	defer l.Disconnect()
+
+	retried := 0
+
+	defer l.Subscribe(func(e Event) {
+		if e.Type == EventOperationRetried {
+			retried++
+		}
+	})()
+
+	if _, err := l.CountDocsCtx(context.Background(), testDB, testCollection, bson.M{}); err == nil || retried == 0 {
+		t.Fatalf("expected a retried failure outside transactions, got %d retries ( %v )", retried, err)
+	}
+
+	retried = 0
+
+	pinned := l.currentClient()
+
+	ctx := context.WithValue(context.Background(), txKey{}, pinned)
+
+	if _, err := l.CountDocsCtx(ctx, testDB, testCollection, bson.M{}); err == nil || retried != 0 {
+		t.Errorf("expected a single attempt inside transactions, got %d retries ( %v )", retried, err)
+	}
+
+	// a reconnection in the middle of a transaction doesn't move its operations to the new client
+	_ = l.reconnect(context.Background(), false, pinned)
+
+	if l.currentClient() == pinned {
+		t.Fatal("expected the client to be replaced")
+	}
+
+	var used *mongo.Client
+
+	_ = l.execute(ctx, "link.CountDocs", testDB, testCollection, true, func(ctx context.Context, client *mongo.Client) error {
+		used = client
+
+		return nil
+	})
+
+	if used != pinned {
+		t.Error("expected the operation to run on the client of the transaction")
+	}
+
+	_ = l.execute(context.Background(), "link.CountDocs", testDB, testCollection, true, func(ctx context.Context, client *mongo.Client) error {
+		used = client
+
+		return nil
+	})
+
+	if used != l.currentClient() {
+		t.Error("expected operations outside transactions to run on the current client")
+	}
+}
+
+func TestHasErrorLabel(t *testing.T) {
+	transient := mongo.CommandError{Code: 112, Name: "WriteConflict", Labels: []string{"TransientTransactionError"}}
+
+	wrapped := fmt.Errorf("callback: %w", &OpError{Op: "UpdateOne", Err: transient})
+
+	if !hasErrorLabel(wrapped, "TransientTransactionError") {
+		t.Error("expected the label of the wrapped command error")
+	}
+
+	if hasErrorLabel(wrapped, "UnknownTransactionCommitResult") || hasErrorLabel(errors.New("plain"), "TransientTransactionError") {
+		t.Error("unexpected label")
+	}
+}
+
+func TestTxOptions(t *testing.T) {
+	o := TxOptions{ReadConcern: readconcern.Snapshot(), WriteConcern: writeconcern.New(writeconcern.WMajority()), MaxCommitTime: time.Second}.transaction()
+
+	if o.ReadConcern == nil || o.WriteConcern == nil || o.MaxCommitTime == nil || *o.MaxCommitTime != time.Second {
+		t.Errorf("expected the given options, got %+v", o)
+	}
+
+	if o := (TxOptions{}).transaction(); o.ReadConcern != nil || o.WriteConcern != nil || o.ReadPreference != nil || o.MaxCommitTime != nil {
+		t.Errorf("expected the client defaults, got %+v", o)
+	}
+}
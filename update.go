@@ -0,0 +1,186 @@
+package mongohelper
+
+import (
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Update is an update document built fluently, like UpdateNew().Set("name", "x").Inc("visits", 1)
+// It marshals as the bson.D it represents, so it's accepted anywhere an update is
+// Updates are immutable: every method returns a new one
+type Update struct {
+	d bson.D
+}
+
+// UpdateNew returns an empty Update, to be filled by its methods
+func UpdateNew() Update {
+	return Update{}
+}
+
+// with returns a copy of u with field: value added to the given operator document
+func (u Update) with(op, field string, value interface{}) Update {
+	d := make(bson.D, 0, len(u.d)+1)
+
+	found := false
+
+	for _, e := range u.d {
+		if e.Key == op {
+			fields := e.Value.(bson.D)
+
+			e.Value = append(fields[:len(fields):len(fields)], bson.E{Key: field, Value: value})
+
+			found = true
+		}
+
+		d = append(d, e)
+	}
+
+	if !found {
+		d = append(d, bson.E{Key: op, Value: bson.D{{Key: field, Value: value}}})
+	}
+
+	return Update{d}
+}
+
+// Set sets field to value
+func (u Update) Set(field string, value interface{}) Update {
+	return u.with("$set", field, value)
+}
+
+// Unset removes the given fields
+func (u Update) Unset(fields ...string) Update {
+	for _, f := range fields {
+		u = u.with("$unset", f, "")
+	}
+
+	return u
+}
+
+// Inc adds n to field, that's created with n when missing
+func (u Update) Inc(field string, n interface{}) Update {
+	return u.with("$inc", field, n)
+}
+
+// Mul multiplies field by n. A missing field is created with 0
+func (u Update) Mul(field string, n interface{}) Update {
+	return u.with("$mul", field, n)
+}
+
+// Min sets field to value when value is lower than the current one
+func (u Update) Min(field string, value interface{}) Update {
+	return u.with("$min", field, value)
+}
+
+// Max sets field to value when value is greater than the current one
+func (u Update) Max(field string, value interface{}) Update {
+	return u.with("$max", field, value)
+}
+
+// Push appends the given values to the array field
+func (u Update) Push(field string, values ...interface{}) Update {
+	return u.with("$push", field, each(values))
+}
+
+// AddToSet appends the given values to the array field, skipping the ones already present
+func (u Update) AddToSet(field string, values ...interface{}) Update {
+	return u.with("$addToSet", field, each(values))
+}
+
+// Pull removes from the array field every element equal to value
+// A Filter removes the elements it matches, like Where("score").Lt(5) for arrays of documents,
+// or Where("").Lt(5) for arrays of scalars
+func (u Update) Pull(field string, value interface{}) Update {
+	if f, ok := value.(Filter); ok {
+		d := f.D()
+
+		if len(d) == 1 && d[0].Key == "" {
+			value = d[0].Value
+		} else {
+			value = d
+		}
+	}
+
+	return u.with("$pull", field, value)
+}
+
+// Rename renames field to newName
+func (u Update) Rename(field, newName string) Update {
+	return u.with("$rename", field, newName)
+}
+
+// CurrentDate sets field to the current date, on server side
+func (u Update) CurrentDate(field string) Update {
+	return u.with("$currentDate", field, true)
+}
+
+// SetOnInsert sets field to value only when the update inserts a new document, through upsert
+func (u Update) SetOnInsert(field string, value interface{}) Update {
+	return u.with("$setOnInsert", field, value)
+}
+
+// D returns the update document
+func (u Update) D() bson.D {
+	if u.d == nil {
+		return bson.D{}
+	}
+
+	return u.d
+}
+
+// MarshalBSON encodes the update document, so Update can be given to the driver as is
+func (u Update) MarshalBSON() ([]byte, error) {
+	return bson.Marshal(u.D())
+}
+
+// each returns the single value, or the $each modifier for many values
+func each(values []interface{}) interface{} {
+	if len(values) == 1 {
+		return values[0]
+	}
+
+	return bson.D{{Key: "$each", Value: bson.A(values)}}
+}
+
+// ValidateUpdate checks that update is a nonempty update document made only of operators, each one with at least one
+// field, or a nonempty aggregation pipeline. UpdateOne and UpdateMany run it before sending the update
+// It returns an *UpdateError, or nil
+func ValidateUpdate(update interface{}) error {
+	if update == nil {
+		return &UpdateError{Reason: "it's nil"}
+	}
+
+	// aggregation pipelines, like mongo.Pipeline or bson.A, but not bson.D, that is also a slice
+	if _, isDoc := update.(bson.D); !isDoc {
+		if v := reflect.ValueOf(update); v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+			if v.Len() == 0 {
+				return &UpdateError{Reason: "the pipeline is empty"}
+			}
+
+			return nil
+		}
+	}
+
+	d, err := toDoc(update)
+
+	if err != nil {
+		return &UpdateError{Reason: err.Error()}
+	}
+
+	if len(d) == 0 {
+		return &UpdateError{Reason: "it's empty"}
+	}
+
+	for _, e := range d {
+		if !strings.HasPrefix(e.Key, "$") {
+			return &UpdateError{Field: e.Key, Reason: "not an update operator; use ReplaceOne to replace documents"}
+		}
+
+		if fields, ok := e.Value.(bson.D); !ok || len(fields) == 0 {
+			return &UpdateError{Field: e.Key, Reason: "the operator needs a document with at least one field"}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package mongohelper
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestUpdate(t *testing.T) {
+	u := UpdateNew().Set("a", 1).Inc("n", 2).Set("b", 2).Push("tags", "x", "y").Unset("old")
+
+	want := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "a", Value: 1}, {Key: "b", Value: 2}}},
+		{Key: "$inc", Value: bson.D{{Key: "n", Value: 2}}},
+		{Key: "$push", Value: bson.D{{Key: "tags", Value: bson.D{{Key: "$each", Value: bson.A{"x", "y"}}}}}},
+		{Key: "$unset", Value: bson.D{{Key: "old", Value: ""}}},
+	}
+
+	if !reflect.DeepEqual(u.D(), want) {
+		t.Errorf("expected %v, got %v", want, u.D())
+	}
+
+	// builders are immutable
+	base := UpdateNew().Set("a", 1)
+	base.Set("b", 2)
+
+	if len(base.D()[0].Value.(bson.D)) != 1 {
+		t.Errorf("expected the base update to be untouched, got %v", base.D())
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	valid := []interface{}{
+		UpdateNew().Set("a", 1),
+		bson.M{"$inc": bson.M{"n": 1}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"a": 1}}}},
+	}
+
+	for _, u := range valid {
+		if err := ValidateUpdate(u); err != nil {
+			t.Errorf("expected %v to be valid, got %v", u, err)
+		}
+	}
+
+	invalid := []interface{}{nil, UpdateNew(), bson.M{}, bson.M{"name": "replacement"}, bson.D{{Key: "$set", Value: bson.D{}}}, mongo.Pipeline{}}
+
+	for _, u := range invalid {
+		if err := ValidateUpdate(u); !errors.Is(err, ErrInvalidUpdate) {
+			t.Errorf("expected %v to be invalid, got %v", u, err)
+		}
+	}
+
+	var l Link
+
+	_, err := l.UpdateMany(testDB, testCollection, bson.M{}, bson.M{"name": "replacement"})
+
+	var ue *UpdateError
+
+	if !errors.As(err, &ue) || ue.Field != "name" || errors.Is(err, ErrNotConnected) {
+		t.Errorf("expected the update to be rejected before reaching the link, got %v", err)
+	}
+}
+
+func TestUpdate_memoryStore(t *testing.T) {
+	m := MemoryStoreNew()
+
+	if _, err := m.InsertOne(testDB, testCollection, bson.M{"_id": 1, "n": 3, "low": 10, "tags": bson.A{"a", "b"}, "old": "x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	u := UpdateNew().Mul("n", 2).Min("low", 5).Max("high", 7).AddToSet("tags", "a", "c").Pull("tags", "b").Rename("old", "new").CurrentDate("at").SetOnInsert("created", true)
+
+	if n, err := m.UpdateOne(testDB, testCollection, bson.M{"_id": 1}, u); err != nil || n != 1 {
+		t.Fatalf("expected 1 updated document, got %d ( %v )", n, err)
+	}
+
+	var doc bson.M
+
+	if err := m.FindOne(testDB, testCollection, bson.M{"_id": 1}, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc["n"] != int32(6) || doc["low"] != int32(5) || doc["high"] != int32(7) || doc["new"] != "x" || doc["old"] != nil || doc["at"] == nil || doc["created"] != nil {
+		t.Errorf("unexpected document %v", doc)
+	}
+
+	if tags := doc["tags"].(bson.A); !reflect.DeepEqual(tags, bson.A{"a", "c"}) {
+		t.Errorf("expected tags [a c], got %v", tags)
+	}
+
+	if _, err := m.UpdateOne(testDB, testCollection, bson.M{"_id": 1}, bson.M{}); !errors.Is(err, ErrInvalidUpdate) {
+		t.Errorf("expected an invalid update error, got %v", err)
+	}
+}
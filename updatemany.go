@@ -2,7 +2,6 @@ package mongohelper
 
 import (
 	"context"
-	"errors"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -15,35 +14,33 @@ import (
 //
 // The update parameter must be a document containing update operators
 // (https://docs.mongodb.com/manual/reference/operator/update/) and can be used to specify the modifications to be made
-// to the selected documents. It cannot be nil or empty.
+// to the selected documents. It cannot be nil or empty, which is checked by ValidateUpdate() before sending.
+// An Update built with UpdateNew() is always valid, once it has at least one operator
 func (l *Link) UpdateMany(database, collection string, filter, update interface{}) (int64, error) {
-	if err := l.linkCheck("link.UpdateMany"); err != nil {
-		return 0, err
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), l.execTimeout())
+	return l.UpdateManyCtx(context.Background(), database, collection, filter, update)
+}
 
-	defer cancel()
+// UpdateManyCtx works like UpdateMany, but honors the cancellation and deadline of the given context
+// The configured execution timeout still applies as an upper bound, including the eventual reconnection
+func (l *Link) UpdateManyCtx(ctx context.Context, database, collection string, filter, update interface{}) (int64, error) {
+	if err := ValidateUpdate(update); err != nil {
+		return 0, &OpError{Op: "UpdateMany", Database: database, Collection: collection, Kind: ErrInvalidUpdate, Err: err}
+	}
 
-	rs, err := l.client.Database(database).Collection(collection).UpdateMany(ctx, filter, update, options.Update())
+	op := Operation{Kind: OpUpdateMany, Database: database, Collection: collection, Filter: filter, Update: update}
 
-	if err != nil {
-		// If not connected, try once again
-		if errors.Is(err, mongo.ErrClientDisconnected) {
-			if err = l.connect(); err != nil {
-				return 0, err
-			}
+	rs, err := l.run(ctx, &op, func(ctx context.Context, client *mongo.Client, op *Operation) (*OpResult, error) {
+		rs, err := client.Database(op.Database).Collection(op.Collection).UpdateMany(ctx, op.Filter, op.Update, options.Update())
 
-			ctx2, cancel2 := context.WithTimeout(context.Background(), l.execTimeout())
+		if err != nil {
+			return nil, err
+		}
 
-			defer cancel2()
+		return &OpResult{MatchedCount: rs.MatchedCount, ModifiedCount: rs.ModifiedCount}, nil
+	})
 
-			if rs, err = l.client.Database(database).Collection(collection).UpdateMany(ctx2, filter, update, options.Update()); err != nil {
-				return 0, err
-			}
-		} else {
-			return 0, err
-		}
+	if err != nil {
+		return 0, err
 	}
 
 	return rs.MatchedCount, nil
@@ -0,0 +1,591 @@
+package mongohelper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FullDocumentMode tells which copies of the changed document a change stream event carries
+type FullDocumentMode string
+
+// Change stream full document modes. WhenAvailable and Required need MongoDB 6.0, with the collection option
+// changeStreamPreAndPostImages enabled
+const (
+	// FullDocumentDefault only carries the document on inserts and replaces
+	FullDocumentDefault FullDocumentMode = "default"
+	// FullDocumentOff doesn't carry the document before the change
+	FullDocumentOff FullDocumentMode = "off"
+	// FullDocumentUpdateLookup looks up the current version of updated documents
+	FullDocumentUpdateLookup FullDocumentMode = "updateLookup"
+	// FullDocumentWhenAvailable carries the pre or post image, if the server still has it
+	FullDocumentWhenAvailable FullDocumentMode = "whenAvailable"
+	// FullDocumentRequired carries the pre or post image, failing the stream when the server doesn't have it
+	FullDocumentRequired FullDocumentMode = "required"
+)
+
+// WatchOptions tunes Watch
+type WatchOptions struct {
+	// Name identifies the subscription in Tokens. Defaults to "database.collection"
+	Name string
+	// Tokens persists the resume tokens. Defaults to a MemoryTokenStore, that only resumes within the process
+	Tokens ResumeTokenStore
+	// FullDocument is the post image mode, for the FullDocument field of the events
+	FullDocument FullDocumentMode
+	// FullDocumentBeforeChange is the pre image mode, for the FullDocumentBeforeChange field of the events
+	FullDocumentBeforeChange FullDocumentMode
+	// BatchSize is how many events are fetched at once
+	BatchSize int32
+	// MaxAwaitTime is how long the server waits for new events before answering an empty batch
+	MaxAwaitTime time.Duration
+}
+
+// ChangeNamespace is the database and collection of a change
+type ChangeNamespace struct {
+	Database   string `bson:"db"`
+	Collection string `bson:"coll"`
+}
+
+// UpdateDescription lists what an update changed
+type UpdateDescription struct {
+	UpdatedFields bson.Raw `bson:"updatedFields"`
+	RemovedFields []string `bson:"removedFields"`
+}
+
+// ChangeEvent is a change stream event
+type ChangeEvent struct {
+	// Token is the resume token of the event
+	Token bson.Raw `bson:"_id"`
+	// OperationType is like insert, update, replace, delete, drop or invalidate
+	OperationType string              `bson:"operationType"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+	Namespace     ChangeNamespace     `bson:"ns"`
+	// DocumentKey holds the _id, and the shard key if any, of the changed document
+	DocumentKey bson.Raw `bson:"documentKey"`
+	// FullDocument is the document after the change, according to WatchOptions.FullDocument
+	FullDocument bson.Raw `bson:"fullDocument"`
+	// FullDocumentBeforeChange is the document before the change, according to WatchOptions.FullDocumentBeforeChange
+	FullDocumentBeforeChange bson.Raw           `bson:"fullDocumentBeforeChange"`
+	UpdateDescription        *UpdateDescription `bson:"updateDescription"`
+	// Raw is the whole event, as received
+	Raw bson.Raw `bson:"-"`
+}
+
+// DecodeFullDocument decodes the document after the change into dest. It returns ErrNotFound if the event has none
+func (e ChangeEvent) DecodeFullDocument(dest interface{}) error {
+	if len(e.FullDocument) == 0 {
+		return ErrNotFound
+	}
+
+	return bson.Unmarshal(e.FullDocument, dest)
+}
+
+// decodeChangeEvent decodes a copy of raw, that the stream reuses
+func decodeChangeEvent(raw bson.Raw) (ChangeEvent, error) {
+	var e ChangeEvent
+
+	raw = append(bson.Raw(nil), raw...)
+
+	if err := bson.Unmarshal(raw, &e); err != nil {
+		return e, err
+	}
+
+	e.Raw = raw
+
+	return e, nil
+}
+
+// Watcher is a change stream subscription, running in a goroutine of its own
+type Watcher struct {
+	link       *Link
+	database   string
+	collection string
+	pipeline   interface{}
+	handler    func(e ChangeEvent) error
+	opts       WatchOptions
+	// token is the resume token of the last handled event
+	token bson.Raw
+	// cancel stops the routine
+	cancel context.CancelFunc
+	// done is closed when the routine returns
+	done chan struct{}
+	once sync.Once
+	// err is why the routine returned
+	err error
+}
+
+// Watch subscribes handler to the changes of a collection. An empty collection watches the whole database
+// pipeline filters and reshapes the events, like PipelineNew().Match(bson.M{"operationType": "insert"}), or is nil
+// The stream is opened before Watch returns, resuming after the token saved in opts.Tokens, if any. Then a goroutine
+// calls handler for each event, one at a time, saving the event token when handler returns nil. Events are delivered
+// at least once: handler may see an event again after a failure
+// When the stream breaks, it's resumed from the last token, as soon as the link is reconnected. The Watcher stops
+// on Stop, Disconnect, the end of the stream, like after an invalidate event, a non transient failure, or an error
+// returned by handler, that Err returns. handler must not call Stop, but may return ErrStopIteration to stop the
+// Watcher with no error
+func (l *Link) Watch(database, collection string, pipeline interface{}, handler func(e ChangeEvent) error, opts WatchOptions) (*Watcher, error) {
+	const routine = "link.Watch"
+
+	if err := l.linkCheck(routine, database, collection); err != nil {
+		return nil, err
+	}
+
+	if opts.Name == "" {
+		opts.Name = database + "." + collection
+	}
+
+	if opts.Tokens == nil {
+		opts.Tokens = MemoryTokenStoreNew()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := &Watcher{
+		link:       l,
+		database:   database,
+		collection: collection,
+		pipeline:   pipeline,
+		handler:    handler,
+		opts:       opts,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+
+	token, err := opts.Tokens.Load(ctx, opts.Name)
+
+	if err != nil {
+		cancel()
+
+		return nil, opError(routine, database, collection, 1, err)
+	}
+
+	w.token = token
+
+	s, client, err := w.open(ctx)
+
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	// an interceptor answered the operation by itself, so there's nothing to watch
+	if s == nil {
+		cancel()
+
+		close(w.done)
+
+		return w, nil
+	}
+
+	l.watchersMu.Lock()
+
+	if l.watchers == nil {
+		l.watchers = make(map[*Watcher]struct{})
+	}
+
+	l.watchers[w] = struct{}{}
+
+	l.watchersMu.Unlock()
+
+	go w.run(ctx, s, client)
+
+	return w, nil
+}
+
+// Stop ends the subscription, waiting for the routine to return, and returns Err. It's safe to call more than once
+func (w *Watcher) Stop() error {
+	w.once.Do(w.cancel)
+
+	<-w.done
+
+	return w.err
+}
+
+// Done is closed when the Watcher stops
+func (w *Watcher) Done() <-chan struct{} {
+	return w.done
+}
+
+// Err returns why the Watcher stopped, or nil if it's running, was stopped by Stop or Disconnect, or its stream ended
+func (w *Watcher) Err() error {
+	select {
+	case <-w.done:
+		return w.err
+	default:
+		return nil
+	}
+}
+
+// run delivers the events of s, opened on client, reopening the stream while it's worth it
+func (w *Watcher) run(ctx context.Context, s changeStream, client *mongo.Client) {
+	const routine = "link.Watch"
+
+	defer close(w.done)
+
+	defer func() {
+		w.link.watchersMu.Lock()
+
+		delete(w.link.watchers, w)
+
+		w.link.watchersMu.Unlock()
+	}()
+
+	policy := w.link.retryPolicy()
+
+	for attempt := uint(1); ; attempt++ {
+		if s != nil {
+			delivered, err := w.deliver(ctx, s)
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			var he *handlerError
+
+			if errors.As(err, &he) {
+				if !errors.Is(he.err, ErrStopIteration) {
+					w.err = opError(routine, w.database, w.collection, 1, he.err)
+				}
+
+				return
+			}
+
+			// the stream ended by itself, like after an invalidate event, and can't be resumed
+			if err == nil {
+				w.link.logger().Info("change stream ended", Field{FieldRoutine, routine}, Field{FieldDatabase, w.database}, Field{FieldCollection, w.collection})
+
+				return
+			}
+
+			if delivered {
+				attempt = 1
+			}
+
+			// a disconnected client or a failing ping mean the link must be reconnected. Other failures stop the
+			// Watcher, unless they are transient
+			if errors.Is(err, mongo.ErrClientDisconnected) || w.link.ping(ctx) != nil {
+				if err := w.link.reconnect(ctx, w.link.insistOnFail(), client); err != nil {
+					w.link.logger().Warn("change stream waiting for reconnection", Field{FieldRoutine, routine}, Field{FieldError, err})
+				}
+			} else if !policy.Retryable(err) {
+				w.err = opError(routine, w.database, w.collection, attempt, err)
+
+				w.link.logger().Error("change stream failed", Field{FieldRoutine, routine}, Field{FieldDatabase, w.database}, Field{FieldCollection, w.collection}, Field{FieldError, err})
+
+				return
+			}
+
+			w.link.logger().Warn("change stream interrupted, resuming", Field{FieldRoutine, routine}, Field{FieldDatabase, w.database}, Field{FieldCollection, w.collection}, Field{FieldAttempt, attempt}, Field{FieldError, err})
+
+			w.link.emit(EventOperationRetried, routine, err)
+		}
+
+		if sleep(ctx, policy.Backoff(attempt)) != nil {
+			return
+		}
+
+		// Disconnect stops the watchers, but they may be reopening meanwhile
+		if w.link.State() == StateClosed {
+			return
+		}
+
+		var err error
+
+		s, client, err = w.open(ctx)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			s = nil
+
+			w.link.logger().Warn("change stream not resumed", Field{FieldRoutine, routine}, Field{FieldAttempt, attempt}, Field{FieldError, err})
+
+			continue
+		}
+
+		// an interceptor answered the operation by itself
+		if s == nil {
+			return
+		}
+	}
+}
+
+// handlerError is an error returned by the Watch handler
+type handlerError struct {
+	err error
+}
+
+func (e *handlerError) Error() string {
+	return e.err.Error()
+}
+
+// deliver hands the events of s to the handler, until the stream breaks. It reports if any event was delivered
+func (w *Watcher) deliver(ctx context.Context, s changeStream) (bool, error) {
+	defer w.link.release(s.close)
+
+	delivered := false
+
+	for s.next(ctx) {
+		e, err := decodeChangeEvent(s.current())
+
+		if err != nil {
+			return delivered, &handlerError{err}
+		}
+
+		if err := w.handler(e); err != nil {
+			return delivered, &handlerError{err}
+		}
+
+		delivered = true
+
+		w.token = s.token()
+
+		// a lost token only means the event may be delivered again
+		if err := w.opts.Tokens.Save(ctx, w.opts.Name, w.token); err != nil && ctx.Err() == nil {
+			w.link.logger().Warn("resume token not saved", Field{FieldRoutine, "link.Watch"}, Field{FieldError, err})
+		}
+	}
+
+	return delivered, s.err()
+}
+
+// open starts the change stream after the last token, through the interceptor chain
+// The stream is nil when an interceptor answered the operation by itself
+func (w *Watcher) open(ctx context.Context) (changeStream, *mongo.Client, error) {
+	op := Operation{Kind: OpWatch, Database: w.database, Collection: w.collection, Pipeline: w.pipeline}
+
+	var (
+		s      changeStream
+		client *mongo.Client
+	)
+
+	_, err := w.link.run(ctx, &op, func(ctx context.Context, c *mongo.Client, op *Operation) (*OpResult, error) {
+		var err error
+
+		s, err = w.stream(ctx, c, op)
+
+		client = c
+
+		return nil, err
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s, client, nil
+}
+
+// stream opens the change stream of op on client
+// The driver doesn't know pre images, so they are asked through an aggregation with a $changeStream stage of our own
+func (w *Watcher) stream(ctx context.Context, client *mongo.Client, op *Operation) (changeStream, error) {
+	db := client.Database(op.Database)
+
+	if op.Database == "" {
+		db = client.Database("admin")
+	}
+
+	if m := w.opts.FullDocumentBeforeChange; m != "" && m != FullDocumentOff {
+		pipeline, err := changeStreamPipeline(w.stage(op.Database == ""), op.Pipeline)
+
+		if err != nil {
+			return nil, err
+		}
+
+		o := options.Aggregate()
+
+		if w.opts.BatchSize > 0 {
+			o.SetBatchSize(w.opts.BatchSize)
+		}
+
+		if w.opts.MaxAwaitTime > 0 {
+			o.SetMaxAwaitTime(w.opts.MaxAwaitTime)
+		}
+
+		var rs *mongo.Cursor
+
+		if op.Collection == "" {
+			rs, err = db.Aggregate(ctx, pipeline, o)
+		} else {
+			rs, err = db.Collection(op.Collection).Aggregate(ctx, pipeline, o)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return cursorStream{rs}, nil
+	}
+
+	o := options.ChangeStream()
+
+	if w.opts.FullDocument != "" {
+		o.SetFullDocument(options.FullDocument(w.opts.FullDocument))
+	}
+
+	if w.token != nil {
+		o.SetResumeAfter(w.token)
+	}
+
+	if w.opts.BatchSize > 0 {
+		o.SetBatchSize(w.opts.BatchSize)
+	}
+
+	if w.opts.MaxAwaitTime > 0 {
+		o.SetMaxAwaitTime(w.opts.MaxAwaitTime)
+	}
+
+	pipeline := op.Pipeline
+
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+
+	var (
+		cs  *mongo.ChangeStream
+		err error
+	)
+
+	switch {
+	case op.Database == "":
+		cs, err = client.Watch(ctx, pipeline, o)
+	case op.Collection == "":
+		cs, err = db.Watch(ctx, pipeline, o)
+	default:
+		cs, err = db.Collection(op.Collection).Watch(ctx, pipeline, o)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return driverStream{cs}, nil
+}
+
+// stage returns the $changeStream stage options, resuming after the last token
+func (w *Watcher) stage(cluster bool) bson.D {
+	d := bson.D{}
+
+	if cluster {
+		d = append(d, bson.E{Key: "allChangesForCluster", Value: true})
+	}
+
+	if w.opts.FullDocument != "" {
+		d = append(d, bson.E{Key: "fullDocument", Value: string(w.opts.FullDocument)})
+	}
+
+	if w.opts.FullDocumentBeforeChange != "" {
+		d = append(d, bson.E{Key: "fullDocumentBeforeChange", Value: string(w.opts.FullDocumentBeforeChange)})
+	}
+
+	if w.token != nil {
+		d = append(d, bson.E{Key: "resumeAfter", Value: w.token})
+	}
+
+	return d
+}
+
+// changeStreamPipeline returns the stages of pipeline after a $changeStream stage with the given options
+func changeStreamPipeline(stage bson.D, pipeline interface{}) (bson.A, error) {
+	a := bson.A{bson.D{{Key: "$changeStream", Value: stage}}}
+
+	if pipeline == nil {
+		return a, nil
+	}
+
+	raw, err := bson.Marshal(bson.D{{Key: "p", Value: pipeline}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	stages, ok := bson.Raw(raw).Lookup("p").ArrayOK()
+
+	if !ok {
+		return nil, errors.New("pipeline must be an array of stages")
+	}
+
+	values, err := stages.Values()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range values {
+		a = append(a, v)
+	}
+
+	return a, nil
+}
+
+// changeStream is the stream of events a Watcher reads
+type changeStream interface {
+	next(ctx context.Context) bool
+	// current returns the event the stream is on. It's overwritten by next
+	current() bson.Raw
+	// token returns a copy of the resume token of the current event
+	token() bson.Raw
+	err() error
+	close(ctx context.Context) error
+}
+
+// driverStream is a driver change stream
+type driverStream struct {
+	cs *mongo.ChangeStream
+}
+
+func (s driverStream) next(ctx context.Context) bool {
+	return s.cs.Next(ctx)
+}
+
+func (s driverStream) current() bson.Raw {
+	return s.cs.Current
+}
+
+func (s driverStream) token() bson.Raw {
+	return append(bson.Raw(nil), s.cs.ResumeToken()...)
+}
+
+func (s driverStream) err() error {
+	return s.cs.Err()
+}
+
+func (s driverStream) close(ctx context.Context) error {
+	return s.cs.Close(ctx)
+}
+
+// cursorStream is a cursor over a $changeStream aggregation
+type cursorStream struct {
+	cursor *mongo.Cursor
+}
+
+func (s cursorStream) next(ctx context.Context) bool {
+	return s.cursor.Next(ctx)
+}
+
+func (s cursorStream) current() bson.Raw {
+	return s.cursor.Current
+}
+
+func (s cursorStream) token() bson.Raw {
+	id, _ := s.cursor.Current.Lookup("_id").DocumentOK()
+
+	return append(bson.Raw(nil), id...)
+}
+
+func (s cursorStream) err() error {
+	return s.cursor.Err()
+}
+
+func (s cursorStream) close(ctx context.Context) error {
+	return s.cursor.Close(ctx)
+}
@@ -0,0 +1,235 @@
+package mongohelper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestResumeTokenStore(t *testing.T) {
+	stores := map[string]ResumeTokenStore{
+		"memory":     MemoryTokenStoreNew(),
+		"collection": CollectionTokenStoreNew(MemoryStoreNew(), testDB, "resumetokens"),
+	}
+
+	ctx := context.Background()
+
+	for name, s := range stores {
+		if token, err := s.Load(ctx, "orders"); err != nil || token != nil {
+			t.Errorf("%s: expected no token, got %v ( %v )", name, token, err)
+		}
+
+		for _, n := range []int32{1, 2} {
+			token, _ := bson.Marshal(bson.M{"_data": n})
+
+			if err := s.Save(ctx, "orders", token); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+
+			if got, err := s.Load(ctx, "orders"); err != nil || !bytes.Equal(got, token) {
+				t.Errorf("%s: expected %v, got %v ( %v )", name, bson.Raw(token), got, err)
+			}
+		}
+
+		if token, _ := s.Load(ctx, "payments"); token != nil {
+			t.Errorf("%s: expected no token for another subscription, got %v", name, token)
+		}
+	}
+}
+
+func TestChangeEvent(t *testing.T) {
+	raw, _ := bson.Marshal(bson.D{
+		{Key: "_id", Value: bson.M{"_data": "token"}},
+		{Key: "operationType", Value: "insert"},
+		{Key: "ns", Value: bson.M{"db": testDB, "coll": testCollection}},
+		{Key: "documentKey", Value: bson.M{"_id": 1}},
+		{Key: "fullDocument", Value: bson.M{"name": "watched", "n": 7}},
+	})
+
+	e, err := decodeChangeEvent(raw)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.OperationType != "insert" || e.Namespace.Collection != testCollection || !bytes.Equal(e.Raw, raw) || e.Token.Lookup("_data").StringValue() != "token" {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	var x testDocStruct
+
+	if err := e.DecodeFullDocument(&x); err != nil || x.Name != "watched" || x.N != 7 {
+		t.Errorf("expected the full document, got %+v ( %v )", x, err)
+	}
+
+	raw, _ = bson.Marshal(bson.D{{Key: "_id", Value: bson.M{"_data": "token"}}, {Key: "operationType", Value: "delete"}})
+
+	if e, _ := decodeChangeEvent(raw); !errors.Is(e.DecodeFullDocument(&x), ErrNotFound) {
+		t.Error("expected ErrNotFound for an event without full document")
+	}
+}
+
+func TestChangeStreamPipeline(t *testing.T) {
+	w := Watcher{opts: WatchOptions{FullDocument: FullDocumentUpdateLookup, FullDocumentBeforeChange: FullDocumentRequired}}
+
+	w.token, _ = bson.Marshal(bson.M{"_data": "token"})
+
+	a, err := changeStreamPipeline(w.stage(false), PipelineNew().Match(bson.M{"operationType": "update"}))
+
+	if err != nil || len(a) != 2 {
+		t.Fatalf("expected 2 stages, got %v ( %v )", a, err)
+	}
+
+	raw, _ := bson.Marshal(bson.M{"p": a})
+
+	stage := bson.Raw(raw).Lookup("p", "0", "$changeStream")
+
+	if stage.Document().Lookup("fullDocumentBeforeChange").StringValue() != "required" || stage.Document().Lookup("resumeAfter", "_data").StringValue() != "token" {
+		t.Errorf("unexpected $changeStream stage %v", stage)
+	}
+
+	if bson.Raw(raw).Lookup("p", "1", "$match", "operationType").StringValue() != "update" {
+		t.Errorf("expected the given stages after $changeStream, got %v", bson.Raw(raw))
+	}
+
+	if _, err := changeStreamPipeline(w.stage(true), "nope"); err == nil {
+		t.Error("expected an error for a pipeline that isn't an array")
+	}
+}
+
+func TestLink_Watch(t *testing.T) {
+	var l Link
+
+	handler := func(ChangeEvent) error { return nil }
+
+	if _, err := l.Watch(testDB, testCollection, nil, handler, WatchOptions{}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("expected ErrNotConnected, got %v", err)
+	}
+
+	u := unreachableLink(t)
+
+	defer u.Disconnect()
+
+	if _, err := u.Watch(testDB, testCollection, nil, handler, WatchOptions{}); err == nil {
+		t.Error("expected the stream not to open")
+	}
+
+	// an interceptor may answer the stream by itself
+	u.Use(func(next OpFunc) OpFunc {
+		return func(ctx context.Context, op *Operation) (*OpResult, error) {
+			if op.Kind == OpWatch {
+				return nil, nil
+			}
+
+			return next(ctx, op)
+		}
+	})
+
+	w, err := u.Watch(testDB, testCollection, nil, handler, WatchOptions{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-w.Done():
+	default:
+		t.Error("expected a stopped Watcher")
+	}
+
+	if err := w.Stop(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+// sliceStream is a change stream over the given events, ending with err
+type sliceStream struct {
+	events []bson.Raw
+	i      int
+	end    error
+	closed bool
+}
+
+func (s *sliceStream) next(context.Context) bool {
+	if s.i >= len(s.events) {
+		return false
+	}
+
+	s.i++
+
+	return true
+}
+
+func (s *sliceStream) current() bson.Raw {
+	return s.events[s.i-1]
+}
+
+func (s *sliceStream) token() bson.Raw {
+	id, _ := s.current().Lookup("_id").DocumentOK()
+
+	return append(bson.Raw(nil), id...)
+}
+
+func (s *sliceStream) err() error {
+	return s.end
+}
+
+func (s *sliceStream) close(context.Context) error {
+	s.closed = true
+
+	return nil
+}
+
+func TestWatcher_streamEnd(t *testing.T) {
+	insert, _ := bson.Marshal(bson.M{"_id": bson.M{"_data": "1"}, "operationType": "insert"})
+	invalidate, _ := bson.Marshal(bson.M{"_id": bson.M{"_data": "2"}, "operationType": "invalidate"})
+
+	var seen []string
+
+	w := &Watcher{
+		link:       linkNew(*NewOptions(testConnectionString)),
+		database:   testDB,
+		collection: testCollection,
+		handler: func(e ChangeEvent) error {
+			seen = append(seen, e.OperationType)
+
+			return nil
+		},
+		opts:   WatchOptions{Name: "orders", Tokens: MemoryTokenStoreNew()},
+		cancel: func() {},
+		done:   make(chan struct{}),
+	}
+
+	s := &sliceStream{events: []bson.Raw{insert, invalidate}}
+
+	w.run(context.Background(), s, nil)
+
+	select {
+	case <-w.Done():
+	default:
+		t.Fatal("expected a stopped Watcher")
+	}
+
+	if err := w.Err(); err != nil {
+		t.Errorf("expected no error when the stream ends by itself, got %v", err)
+	}
+
+	if err := w.Stop(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	if len(seen) != 2 || seen[1] != "invalidate" {
+		t.Errorf("expected both events, got %v", seen)
+	}
+
+	if !s.closed {
+		t.Error("expected the stream to be closed")
+	}
+
+	if token, _ := w.opts.Tokens.Load(context.Background(), "orders"); !bytes.Equal(token, s.token()) {
+		t.Errorf("expected the last token to be saved, got %v", token)
+	}
+}